@@ -0,0 +1,88 @@
+// пакет с настройками приложения
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/nextdate"
+)
+
+// настройки приложения
+type Config struct {
+	Port           string   // порт, который слушает сервер
+	DBFile         string   // файл БД SQLite
+	DSN            string   // строка подключения к хранилищу, пустая - SQLite в DBFile
+	Password       string   // пароль для доступа, пустой - без аутентификации
+	Holidays       []string // файлы календаря праздников
+	TimeZone       string   // часовой пояс по умолчанию для задач без своего пояса
+	TrashRetention string   // срок хранения задач в корзине: 30d или 720h, пустой - DefaultTrashRetention
+
+	// часовой пояс TimeZone и календарь из файлов Holidays, загруженные функцией LoadDates
+	Dates nextdate.Settings
+}
+
+// файл БД по умолчанию
+const DefaultDBFile = "scheduler.db"
+
+// срок хранения задач в корзине по умолчанию
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// функция чтения настроек из переменных среды
+func FromEnv() Config {
+	cfg := Config{
+		Port:           os.Getenv("TODO_PORT"),
+		DBFile:         os.Getenv("TODO_DBFILE"),
+		DSN:            os.Getenv("TODO_DSN"),
+		Password:       os.Getenv("TODO_PASSWORD"),
+		TimeZone:       os.Getenv("TODO_TZ"),
+		TrashRetention: os.Getenv("TODO_TRASH_RETENTION"),
+	}
+	if cfg.DBFile == "" {
+		cfg.DBFile = DefaultDBFile
+	}
+	// календари праздников через разделитель путей, например holidays.ics:transfers.json
+	if holidays := os.Getenv("TODO_HOLIDAYS"); holidays != "" {
+		cfg.Holidays = filepath.SplitList(holidays)
+	}
+	return cfg
+}
+
+// функция загрузки часового пояса и календарей праздников, указанных в настройках
+func (cfg *Config) LoadDates() error {
+	dates, err := nextdate.LoadSettings(cfg.TimeZone, cfg.Holidays)
+	if err != nil {
+		return err
+	}
+	cfg.Dates = dates
+	return nil
+}
+
+// функция разбора срока хранения задач в корзине: количество дней (30d) или длительность (12h, 90m)
+func ParseRetention(str string) (time.Duration, error) {
+	if str == "" {
+		return DefaultTrashRetention, nil
+	}
+	var retention time.Duration
+	if days, ok := strings.CutSuffix(str, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("wrong trash retention %q: %w", str, err)
+		}
+		retention = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if retention, err = time.ParseDuration(str); err != nil {
+			return 0, fmt.Errorf("wrong trash retention %q: %w", str, err)
+		}
+	}
+	if retention <= 0 {
+		return 0, errors.New("trash retention must be positive")
+	}
+	return retention, nil
+}
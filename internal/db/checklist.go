@@ -0,0 +1,185 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// пункт чек-листа задачи; пункты с ParentId вложены в другой пункт той же задачи
+type ChecklistItem struct {
+	Id     int `json:"id,string"`
+	TaskId int `json:"task_id,string"`
+	// айди пункта, в который вложен этот, 0 - пункт верхнего уровня
+	ParentId int    `json:"parent_id,string,omitempty"`
+	Text     string `json:"text"`
+	Done     bool   `json:"done"`
+	// место пункта среди пунктов того же уровня, пункты упорядочены по нему и по айди
+	Position int `json:"position"`
+}
+
+// сколько пунктов чек-листа задачи выполнено из скольких
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// ошибка для операций с пунктом, которого нет у задачи
+var ErrItemNotFound = errors.New("checklist item not found")
+
+// функция возвращает прогресс или nil, если пунктов нет
+func newProgress(done, total int) *Progress {
+	if total == 0 {
+		return nil
+	}
+	return &Progress{Done: done, Total: total}
+}
+
+// функция чтения пунктов чек-листа задачи из SQLite или PostgreSQL
+func queryChecklist(db *sql.DB, taskId int) ([]*ChecklistItem, error) {
+	rows, err := db.Query(`SELECT id,task_id,parent_id,text,done,position FROM checklist_items
+		WHERE task_id=$1 ORDER BY parent_id,position,id`, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("error while query for checklist: %w", err)
+	}
+	defer rows.Close()
+	items := []*ChecklistItem{}
+	for rows.Next() {
+		item := ChecklistItem{}
+		if err := rows.Scan(&item.Id, &item.TaskId, &item.ParentId, &item.Text, &item.Done, &item.Position); err != nil {
+			return nil, fmt.Errorf("error while scan checklist: %w", err)
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	return items, nil
+}
+
+// функция проверяет, что пункт id есть у задачи
+func checkItem(tx *sql.Tx, taskId, id int) error {
+	var found int
+	err := tx.QueryRow("SELECT id FROM checklist_items WHERE id=$1 AND task_id=$2", id, taskId).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrItemNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("can't get checklist item: %w", err)
+	}
+	return nil
+}
+
+// функция добавления пункта в SQLite или PostgreSQL; новый пункт встает после пунктов того же уровня
+func addChecklistItem(db *sql.DB, item *ChecklistItem) (int64, error) {
+	var id int64
+	err := inTx(db, func(tx *sql.Tx) error {
+		if err := checkTaskExists(tx, item.TaskId); err != nil {
+			return err
+		}
+		if item.ParentId != 0 {
+			if err := checkItem(tx, item.TaskId, item.ParentId); err != nil {
+				return err
+			}
+		}
+		err := tx.QueryRow(`INSERT INTO checklist_items (task_id,parent_id,text,done,position)
+			SELECT $1,$2,$3,$4,coalesce(max(position),0)+1 FROM checklist_items WHERE task_id=$1 AND parent_id=$2
+			RETURNING id`, item.TaskId, item.ParentId, item.Text, item.Done).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("can't insert checklist item: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// функция изменения текста, отметки и места пункта в SQLite или PostgreSQL
+func updChecklistItem(db *sql.DB, item *ChecklistItem) error {
+	res, err := db.Exec("UPDATE checklist_items SET text=$1,done=$2,position=$3 WHERE id=$4 AND task_id=$5",
+		item.Text, item.Done, item.Position, item.Id, item.TaskId)
+	if err != nil {
+		return fmt.Errorf("can't update checklist item: %w", err)
+	}
+	if err := checkAffected(res); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrItemNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// функция удаления пункта вместе со всеми вложенными в него из SQLite или PostgreSQL
+func delChecklistItem(db *sql.DB, taskId, id int) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if err := checkItem(tx, taskId, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`WITH RECURSIVE sub(id) AS (
+				SELECT id FROM checklist_items WHERE id=$1
+				UNION ALL SELECT c.id FROM checklist_items c JOIN sub ON c.parent_id=sub.id
+			) DELETE FROM checklist_items WHERE id IN (SELECT id FROM sub)`, id)
+		if err != nil {
+			return fmt.Errorf("can't delete checklist item: %w", err)
+		}
+		return nil
+	})
+}
+
+// функция удаления чек-листа задачи, например вместе с задачей
+func delTaskChecklist(tx execer, taskId int) error {
+	if _, err := tx.Exec("DELETE FROM checklist_items WHERE task_id=$1", taskId); err != nil {
+		return fmt.Errorf("can't delete task checklist: %w", err)
+	}
+	return nil
+}
+
+// функция чтения прогресса чек-листов задач одним запросом
+func loadProgress(db *sql.DB, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byId := make(map[int]*Task, len(tasks))
+	params := make([]string, 0, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, task := range tasks {
+		byId[task.Id] = task
+		args = append(args, task.Id)
+		params = append(params, fmt.Sprintf("$%d", len(args)))
+	}
+	rows, err := db.Query("SELECT task_id,sum(CASE WHEN done THEN 1 ELSE 0 END),count(*) FROM checklist_items WHERE task_id IN ("+
+		strings.Join(params, ",")+") GROUP BY task_id", args...)
+	if err != nil {
+		return fmt.Errorf("error while query for checklist progress: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, done, total int
+		if err := rows.Scan(&id, &done, &total); err != nil {
+			return fmt.Errorf("error while scan checklist progress: %w", err)
+		}
+		if task := byId[id]; task != nil {
+			task.Progress = newProgress(done, total)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("some error in cursor: %w", err)
+	}
+	return nil
+}
+
+// функция чтения меток, прогресса чек-листов и зависимостей задач
+func loadDetails(db *sql.DB, tasks []*Task) error {
+	if err := loadTags(db, tasks); err != nil {
+		return err
+	}
+	if err := loadProgress(db, tasks); err != nil {
+		return err
+	}
+	return loadBlocked(db, tasks)
+}
@@ -0,0 +1,251 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// запись о выполнении повторения задачи
+type Completion struct {
+	Id     int `json:"id,string"`
+	TaskId int `json:"task_id,string"`
+	// заголовок задачи, читается из задачи и в записи не хранится
+	Title string `json:"title"`
+	// дата выполненного повторения
+	Date string `json:"date"`
+	// время выполнения в формате RFC 3339
+	CompletedAt string `json:"completed_at"`
+	// исходная дата повторения по правилу (отличается от Date, если повторение переносили)
+	// и сколько повторений оставалось до выполнения; нужны, чтобы отменить выполнение
+	Origin    string `json:"-"`
+	Remaining int    `json:"-"`
+	// колонка задачи и айди отмеченных пунктов чек-листа до выполнения, их тоже возвращает отмена;
+	// у записей, сделанных до появления этих полей, колонка пустая и отмена их не трогает
+	Status  string `json:"-"`
+	Checked []int  `json:"-"`
+}
+
+// переход задачи на следующее повторение после выполнения или пропуска; хранилище записывает его целиком
+// или не записывает совсем
+type Advance struct {
+	TaskId int
+	// дата серии, которая становится обычным исключением (пропущенное или перенесенное повторение), или пустая
+	Exception string
+	// время переноса в архив; если пустое, задача переходит на дату Date с Remaining оставшимися повторениями,
+	// отметки чек-листа снимаются, а задача возвращается в колонку StatusTodo
+	Archived  string
+	Date      string
+	Remaining int
+	// выполнение для истории, nil при пропуске; отмеченные пункты чек-листа хранилище записывает в него само
+	Completion *Completion
+}
+
+// ошибка для отмены выполнения, когда выполнений нет
+var ErrCompletionNotFound = errors.New("no completions")
+
+// функция записи айди через запятую
+func formatIds(ids []int) string {
+	strs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strs = append(strs, strconv.Itoa(id))
+	}
+	return strings.Join(strs, ",")
+}
+
+// функция чтения айди, записанных через запятую
+func parseIds(str string) ([]int, error) {
+	if str == "" {
+		return nil, nil
+	}
+	ids := []int{}
+	for _, s := range strings.Split(str, ",") {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert ID to int: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// функция перехода задачи на следующее повторение или в архив в SQLite или PostgreSQL
+// вместе с исключением, сбросом чек-листа и записью выполнения в одной транзакции
+func advanceTask(db *sql.DB, a *Advance) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if a.Exception != "" {
+			if err := saveException(tx, &Exception{TaskId: a.TaskId, Date: a.Exception}); err != nil {
+				return err
+			}
+		}
+		if a.Completion != nil {
+			// отметки запоминаем до того, как новое повторение их снимет
+			c := *a.Completion
+			var err error
+			if c.Checked, err = checkedItems(tx, a.TaskId); err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO completions (task_id,date,origin,remaining,status,checked,completed_at)
+				VALUES ($1,$2,$3,$4,$5,$6,$7)`, c.TaskId, c.Date, c.Origin, c.Remaining, c.Status, formatIds(c.Checked), c.CompletedAt)
+			if err != nil {
+				return fmt.Errorf("can't insert completion: %w", err)
+			}
+		}
+		if a.Archived != "" {
+			res, err := tx.Exec("UPDATE scheduler SET archived_at=$1 WHERE id=$2 AND archived_at='' AND deleted_at=''",
+				a.Archived, a.TaskId)
+			if err != nil {
+				return fmt.Errorf("can't archive task: %w", err)
+			}
+			return checkAffected(res)
+		}
+		res, err := tx.Exec("UPDATE scheduler SET date=$1,remaining=$2,status=$3 WHERE id=$4 AND archived_at='' AND deleted_at=''",
+			a.Date, a.Remaining, StatusTodo, a.TaskId)
+		if err != nil {
+			return fmt.Errorf("can't update task date: %w", err)
+		}
+		if err := checkAffected(res); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE checklist_items SET done=$1 WHERE task_id=$2", false, a.TaskId); err != nil {
+			return fmt.Errorf("can't reset checklist: %w", err)
+		}
+		return nil
+	})
+}
+
+// функция чтения айди отмеченных пунктов чек-листа задачи
+func checkedItems(tx querier, taskId int) ([]int, error) {
+	rows, err := tx.Query("SELECT id FROM checklist_items WHERE task_id=$1 AND done=$2 ORDER BY id", taskId, true)
+	if err != nil {
+		return nil, fmt.Errorf("error while query for checklist: %w", err)
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error while scan checklist: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	return ids, nil
+}
+
+// функция чтения выполнений с датами повторений от from до to включительно (пустая граница не ограничивает)
+// из SQLite или PostgreSQL, сначала последние
+func queryCompletions(db *sql.DB, from, to string, limit int) ([]*Completion, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if from != "" {
+		where = append(where, "c.date>="+arg(from))
+	}
+	if to != "" {
+		where = append(where, "c.date<="+arg(to))
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := db.Query(`SELECT c.id,c.task_id,s.title,c.date,c.completed_at,c.origin,c.remaining,c.status,c.checked
+		FROM completions c JOIN scheduler s ON s.id=c.task_id AND s.deleted_at=''`+cond+" ORDER BY c.id DESC LIMIT "+arg(limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error while query for completions: %w", err)
+	}
+	defer rows.Close()
+	completions := []*Completion{}
+	for rows.Next() {
+		c := Completion{}
+		var checked string
+		if err := rows.Scan(&c.Id, &c.TaskId, &c.Title, &c.Date, &c.CompletedAt, &c.Origin, &c.Remaining,
+			&c.Status, &checked); err != nil {
+			return nil, fmt.Errorf("error while scan completions: %w", err)
+		}
+		if c.Checked, err = parseIds(checked); err != nil {
+			return nil, err
+		}
+		completions = append(completions, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	return completions, nil
+}
+
+// функция чтения последнего выполнения из SQLite или PostgreSQL
+func lastCompletion(db *sql.DB) (*Completion, error) {
+	completions, err := queryCompletions(db, "", "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(completions) == 0 {
+		return nil, ErrCompletionNotFound
+	}
+	return completions[0], nil
+}
+
+// функция отмены выполнения в SQLite или PostgreSQL: задача возвращается на дату повторения
+// с прежним количеством оставшихся повторений, колонкой и отметками чек-листа и уходит из архива,
+// перенос повторения восстанавливается, а запись о выполнении удаляется
+func undoCompletion(db *sql.DB, c *Completion) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM completions WHERE id=$1", c.Id)
+		if err != nil {
+			return fmt.Errorf("can't delete completion: %w", err)
+		}
+		if err := checkAffected(res); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrCompletionNotFound
+			}
+			return err
+		}
+		res, err = tx.Exec("UPDATE scheduler SET date=$1,remaining=$2,archived_at='' WHERE id=$3 AND deleted_at=''",
+			c.Date, c.Remaining, c.TaskId)
+		if err != nil {
+			return fmt.Errorf("can't restore task: %w", err)
+		}
+		if err := checkAffected(res); err != nil {
+			return err
+		}
+		// при выполнении перенесенное повторение стало обычным исключением, возвращаем перенос
+		if c.Origin != c.Date {
+			if err := saveException(tx, &Exception{TaskId: c.TaskId, Date: c.Origin, NewDate: c.Date}); err != nil {
+				return err
+			}
+		}
+		if c.Status == "" {
+			return nil
+		}
+		if _, err := tx.Exec("UPDATE scheduler SET status=$1 WHERE id=$2", c.Status, c.TaskId); err != nil {
+			return fmt.Errorf("can't restore task status: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE checklist_items SET done=$1 WHERE task_id=$2", false, c.TaskId); err != nil {
+			return fmt.Errorf("can't restore checklist: %w", err)
+		}
+		// пункты, удаленные после выполнения, просто не найдутся
+		for _, id := range c.Checked {
+			if _, err := tx.Exec("UPDATE checklist_items SET done=$1 WHERE id=$2 AND task_id=$3", true, id, c.TaskId); err != nil {
+				return fmt.Errorf("can't restore checklist: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// функция удаления выполнений задачи, например вместе с задачей
+func delTaskCompletions(tx execer, taskId int) error {
+	if _, err := tx.Exec("DELETE FROM completions WHERE task_id=$1", taskId); err != nil {
+		return fmt.Errorf("can't delete task completions: %w", err)
+	}
+	return nil
+}
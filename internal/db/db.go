@@ -2,220 +2,158 @@
 package db
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
+	"strings"
 	"time"
-
-	_ "modernc.org/sqlite"
 )
 
 // формат хранения даты в строке
 const TmFormat string = "20060102"
 
-var db *sql.DB
-
-// функция инициализации БД
-func Init(dbFileName string) error {
-	// проверяем наличие файла с БД
-	_, err := os.Stat(dbFileName)
-	var newDb bool
-	// если его нет, то создаем
-	if err != nil {
-		dbFile, err := os.OpenFile(dbFileName, os.O_CREATE, 0644)
-		if err != nil {
-			return fmt.Errorf("can't open db-file: %w", err)
-		}
-		dbFile.Close()
-		newDb = true
-	}
-	// подключаемся к БД
-	db, err = sql.Open("sqlite", dbFileName)
-	if err != nil {
-		return fmt.Errorf("can't connect to database: %w", err)
-	}
-	//если создали файл, то создаем в нем таблицу и индекс
-	if newDb {
-		_, err := db.Exec(`CREATE TABLE scheduler (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			date CHAR(8) NOT NULL DEFAULT "",
-			title VARCHAR(256) NOT NULL DEFAULT "задача",
-			comment TEXT NOT NULL DEFAULT "",
-			repeat VARCHAR(128) NOT NULL DEFAULT ""
-		)`)
-		if err != nil {
-			return fmt.Errorf("error while creating table: %w", err)
-		}
-		_, err = db.Exec(`CREATE INDEX date_scheduler ON scheduler (date)`)
-		if err != nil {
-			return fmt.Errorf("error while creating index on scheduler: %w", err)
-		}
-	}
-
-	return nil
-}
-
-// функция для зарытия БД из других пакетов
-func CloseDb() {
-	if db != nil {
-		db.Close()
-	}
-}
+// колонки задачи в том порядке, в котором их возвращает taskFields
+const taskColumns = "date,title,comment,repeat,remaining,repeat_mode,due_time,timezone,created_at,list_id,priority,status,estimate,archived_at,deleted_at"
 
-// функция добавления новой записи в БД
-func AddTask(task *Task) (int64, error) {
-	var id int64
-	res, err := db.Exec("INSERT INTO scheduler (date,title,comment,repeat) VALUES (:date,:title,:comment,:repeat)",
-		sql.Named("date", task.Date),
-		sql.Named("title", task.Title),
-		sql.Named("comment", task.Comment),
-		sql.Named("repeat", task.Repeat))
-	if err != nil {
-		return 0, fmt.Errorf("can't insert new task: %w", err)
-	}
-	id, err = res.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("can't get index of inserted task: %w", err)
-	}
-	return id, nil
+// функция возвращает указатели на поля задачи для Scan в порядке taskColumns
+func taskFields(task *Task) []any {
+	return []any{&task.Date, &task.Title, &task.Comment, &task.Repeat, &task.Remaining,
+		&task.RepeatMode, &task.DueTime, &task.TimeZone, &task.Created, &task.ListId,
+		&task.Priority, &task.Status, &task.Estimate, &task.Archived, &task.Deleted}
 }
 
-// функция чтения заданного количества записей из базы
-func Tasks(limit int) ([]*Task, error) {
-	// слайс, в который читаем
-	tasks := make([]*Task, 0, limit)
-	// эскуэль запрос
-	rows, err := db.Query("SELECT id,date,title,comment,repeat FROM scheduler ORDER BY date LIMIT :limit",
-		sql.Named("limit", limit))
-	if err != nil {
-		return nil, fmt.Errorf("error while SELECT query: %w", err)
-	}
-	defer rows.Close()
-	// бежим по строкам
-	for rows.Next() {
-		task := Task{}
-		err := rows.Scan(&task.Id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
-			return nil, fmt.Errorf("error while scan table: %w", err)
-		}
-		//и заполняем слайс
-		tasks = append(tasks, &task)
-	}
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("some error in cursor: %w", err)
-	}
-
-	return tasks, nil
+// ошибка для операций с задачей, которой нет в хранилище
+var ErrNotFound = errors.New("task not found")
+
+// хранилище задач; реализации: SQLiteStore, PostgresStore и MemoryStore
+type TaskStore interface {
+	// добавление задачи, возвращает ее айди
+	AddTask(task *Task) (int64, error)
+	// страница списка задач с порядком, фильтрами и курсором
+	ListTasks(q TaskQuery) (*TaskPage, error)
+	GetTask(id string) (*Task, error)
+	// изменение всех полей задачи
+	UpdTask(task *Task) error
+	// изменение даты задачи и количества оставшихся повторений
+	UpDateTask(next string, remaining int, id string) error
+	// окончательное удаление задачи вместе с ее исключениями и выполнениями
+	DelTask(id string) error
+	AddException(ex *Exception) error
+	DelException(taskId int, date string) error
+	Exceptions(taskId int) ([]*Exception, error)
+	// все метки с количеством задач, сначала самые частые
+	Tags() ([]*Tag, error)
+	// переименование метки, ErrTagExists, если метка с новым именем уже есть; возвращает метку с количеством
+	// задач, как в Tags, и с нулевым, если метка есть только у задач из архива или корзины
+	RenameTag(name, newName string) (*Tag, error)
+	// перенос задач с метки name на метку into, после чего name удаляется; возвращает метку into, как RenameTag
+	MergeTag(name, into string) (*Tag, error)
+	// удаление метки у всех задач
+	DelTag(name string) error
+	// все списки с количеством задач в порядке Position
+	Lists() ([]*List, error)
+	GetList(id int) (*List, error)
+	// добавление списка после остальных, возвращает его айди
+	AddList(list *List) (int64, error)
+	// изменение имени, цвета и места списка
+	UpdList(list *List) error
+	// удаление списка; его задачи переносятся в список moveTo, а если он нулевой - в корзину со временем at
+	DelList(id, moveTo int, at string) error
+	// перенос задачи в другой список
+	MoveTask(id string, listId int) error
+	// перенос задачи в другую колонку доски
+	SetStatus(id string, status string) error
+	// пункты чек-листа задачи: сначала верхнего уровня, затем вложенные, по месту среди соседей
+	ChecklistItems(taskId int) ([]*ChecklistItem, error)
+	// добавление пункта после пунктов того же уровня, возвращает его айди
+	AddChecklistItem(item *ChecklistItem) (int64, error)
+	// изменение текста, отметки и места пункта
+	UpdChecklistItem(item *ChecklistItem) error
+	// удаление пункта вместе с вложенными в него
+	DelChecklistItem(taskId, id int) error
+	// добавление зависимости задачи taskId от dependsOn, ErrDependencyCycle, если она замыкает цикл
+	AddDependency(taskId, dependsOn int) error
+	DelDependency(taskId, dependsOn int) error
+	// задачи выше и ниже задачи по цепочке зависимостей
+	TaskGraph(taskId int) (*Graph, error)
+	// переход задачи на следующее повторение или в архив после выполнения или пропуска одной транзакцией:
+	// исключение, новая дата, сброс чек-листа и колонки и запись выполнения вместе с состоянием задачи до него
+	AdvanceTask(a *Advance) error
+	// выполнения с датами повторений от from до to включительно, сначала последние
+	Completions(from, to string, limit int) ([]*Completion, error)
+	// последнее выполнение, ErrCompletionNotFound, если выполнений нет
+	LastCompletion() (*Completion, error)
+	// отмена выполнения: задача возвращается на дату повторения с прежними колонкой, отметками чек-листа
+	// и переносом повторения и уходит из архива
+	UndoCompletion(c *Completion) error
+	// перенос задачи в корзину: задача больше нигде не видна, пока ее не вернут или не удалят окончательно
+	TrashTask(id string, at string) error
+	// задачи из корзины, сначала последние удаленные
+	Trash(limit int) ([]*Task, error)
+	// возврат задачи из корзины, ErrNotFound, если в корзине ее нет
+	RestoreTask(id string) error
+	// окончательное удаление задач, попавших в корзину раньше before, возвращает их количество
+	PurgeTrash(before string) (int, error)
+	Close() error
 }
 
-// функция запроса записи БД по айди
-func GetTask(id string) (*Task, error) {
-	var task Task
-	var err error
-	task.Id, err = strconv.Atoi(id)
-	if err != nil {
-		return nil, fmt.Errorf("can't convert ID to int: %w", err)
-	}
-	row := db.QueryRow("SELECT date,title,comment,repeat FROM scheduler WHERE id=:id", sql.Named("id", id))
-	return &task, row.Scan(&task.Date, &task.Title, &task.Comment, &task.Repeat)
-}
-
-// функция изменения всех полей записи БД по айди
-func UpdTask(task *Task) error {
-	// запросили
-	res, err := db.Exec("UPDATE scheduler SET date=:date,title=:title,comment=:comment,repeat=:repeat WHERE id=:id",
-		sql.Named("date", task.Date),
-		sql.Named("title", task.Title),
-		sql.Named("comment", task.Comment),
-		sql.Named("repeat", task.Repeat),
-		sql.Named("id", task.Id))
-	if err != nil {
-		return fmt.Errorf("can't update task: %w", err)
-	}
-	// проверили количество измененных
-	num, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("can't check updated rows: %w", err)
-	}
-	// если их нет, то ошибка
-	if num == 0 {
-		return fmt.Errorf("incorrect id")
-	}
+var (
+	_ TaskStore = (*SQLiteStore)(nil)
+	_ TaskStore = (*PostgresStore)(nil)
+	_ TaskStore = (*MemoryStore)(nil)
+	_ Migrator  = (*SQLiteStore)(nil)
+	_ Migrator  = (*PostgresStore)(nil)
+)
 
-	return nil
+// хранилище со схемой, которая меняется по шагам
+type Migrator interface {
+	// применение всех новых шагов
+	Migrate() error
+	// откат последних steps шагов
+	Rollback(steps int) error
+	MigrationStatus() ([]MigrationState, error)
 }
 
-// функция удаления записи по айди
-func DelTask(id string) error {
-	res, err := db.Exec("DELETE FROM scheduler WHERE id=:id", sql.Named("id", id))
-	if err != nil {
-		return fmt.Errorf("can't delete task: %w", err)
-	}
-	//проверяем, что удалилась
-	num, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("can't check deleted rows: %w", err)
-	}
-
-	if num == 0 {
-		return fmt.Errorf("incorrect id")
+// функция открытия хранилища по строке подключения без изменения схемы:
+// postgres://... или postgresql://... - PostgreSQL, memory: - в памяти,
+// sqlite:файл или пустая строка - SQLite в файле (по умолчанию dbFile)
+func OpenStore(dsn, dbFile string) (TaskStore, error) {
+	scheme, rest, _ := strings.Cut(dsn, ":")
+	switch scheme {
+	case "":
+		return OpenSQLite(dbFile)
+	case "sqlite":
+		if file := strings.TrimPrefix(rest, "//"); file != "" {
+			dbFile = file
+		}
+		return OpenSQLite(dbFile)
+	case "postgres", "postgresql":
+		return OpenPostgres(dsn)
+	case "memory":
+		return NewMemory(), nil
 	}
-
-	return nil
+	return nil, fmt.Errorf("unknown database type %q", scheme)
 }
 
-// функция изменения поля с датой записи
-func UpDateTask(next string, id string) error {
-	res, err := db.Exec("UPDATE scheduler SET date=:date WHERE id=:id",
-		sql.Named("date", next),
-		sql.Named("id", id))
+// функция открытия хранилища и применения новых шагов изменения схемы
+func NewStore(dsn, dbFile string) (TaskStore, error) {
+	store, err := OpenStore(dsn, dbFile)
 	if err != nil {
-		return fmt.Errorf("can't update task date: %w", err)
+		return nil, err
 	}
-	// проверяем, запись нашлась
-	num, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("can't check updated task date: %w", err)
-	}
-
-	if num == 0 {
-		return fmt.Errorf("incorrect id")
+	if m, ok := store.(Migrator); ok {
+		if err := m.Migrate(); err != nil {
+			store.Close()
+			return nil, err
+		}
 	}
-	return nil
+	return store, nil
 }
 
-// функция поиска записей в базе по словам в заголовке и коментах или дате формата 02.01.2006
-func TasksSearchStr(limit int, str string) ([]*Task, error) {
-	// слайс, в который читаем
-	tasks := make([]*Task, 0, limit)
-	query := "SELECT * FROM scheduler WHERE title LIKE :search OR comment LIKE :search ORDER BY date LIMIT :limit"
-	search := "%" + str + "%"
-	// если задана дата в нужном формате, то меняем запрос
+// функция проверяет, что строка поиска - дата формата 02.01.2006, и возвращает ее в формате хранения
+func searchDate(str string) (string, bool) {
 	date, err := time.Parse("02.01.2006", str)
-	if err == nil {
-		search = date.Format(TmFormat)
-		query = "SELECT * FROM scheduler WHERE date = :search ORDER BY date LIMIT :limit"
-	}
-	// эскуэль запрос
-	rows, err := db.Query(query, sql.Named("search", search), sql.Named("limit", limit))
 	if err != nil {
-		return nil, fmt.Errorf("error while query for search: %w", err)
-	}
-	defer rows.Close()
-	// бежим по строкам
-	for rows.Next() {
-		task := Task{}
-		err := rows.Scan(&task.Id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
-			return nil, fmt.Errorf("error while scan for search: %w", err)
-		}
-		//и заполняем слайс
-		tasks = append(tasks, &task)
-	}
-	if rows.Err() != nil {
-		return nil, fmt.Errorf("some error in cursor: %w", err)
+		return "", false
 	}
-
-	return tasks, nil
+	return date.Format(TmFormat), true
 }
@@ -0,0 +1,296 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// зависимость: задачу TaskId нельзя выполнить, пока не выполнена задача DependsOn
+type Dependency struct {
+	TaskId    int `json:"task_id,string"`
+	DependsOn int `json:"depends_on,string"`
+}
+
+// цепочка зависимостей задачи
+type Graph struct {
+	// задачи, которые нужно выполнить до этой, сначала ближайшие
+	Upstream []*Task `json:"upstream"`
+	// задачи, которые ждут эту, сначала ближайшие
+	Downstream []*Task `json:"downstream"`
+	// зависимости между задачей и задачами цепочки
+	Edges []*Dependency `json:"edges"`
+}
+
+// ошибки для операций с зависимостями
+var (
+	ErrDependencyNotFound = errors.New("dependency not found")
+	ErrDependencyCycle    = errors.New("dependency would create a cycle")
+)
+
+// функция обхода зависимостей в ширину от задачи id по связям next; возвращает найденные задачи без самой id
+func walkDeps(id int, next map[int][]int) []int {
+	seen := map[int]bool{id: true}
+	var found []int
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		for _, other := range next[queue[0]] {
+			if !seen[other] {
+				seen[other] = true
+				found = append(found, other)
+				queue = append(queue, other)
+			}
+		}
+	}
+	return found
+}
+
+// функция возвращает связи зависимостей в обе стороны: от задачи к тем, от кого она зависит, и обратно
+func depLinks(edges []*Dependency) (up, down map[int][]int) {
+	up, down = make(map[int][]int), make(map[int][]int)
+	for _, edge := range edges {
+		up[edge.TaskId] = append(up[edge.TaskId], edge.DependsOn)
+		down[edge.DependsOn] = append(down[edge.DependsOn], edge.TaskId)
+	}
+	return up, down
+}
+
+// функция проверяет, что новая зависимость задачи taskId от dependsOn не замыкает цикл:
+// задача dependsOn не должна уже зависеть от taskId, в том числе через другие задачи
+func checkCycle(edges []*Dependency, taskId, dependsOn int) error {
+	if taskId == dependsOn {
+		return ErrDependencyCycle
+	}
+	up, _ := depLinks(edges)
+	for _, id := range walkDeps(dependsOn, up) {
+		if id == taskId {
+			return ErrDependencyCycle
+		}
+	}
+	return nil
+}
+
+// функция возвращает айди задач выше и ниже задачи taskId по цепочке и зависимости между задачами цепочки;
+// зависимости edges должны быть упорядочены, как после sortDeps
+func graphIds(edges []*Dependency, taskId int) (upstream, downstream []int, chain []*Dependency) {
+	up, down := depLinks(edges)
+	upstream, downstream = walkDeps(taskId, up), walkDeps(taskId, down)
+	inChain := map[int]bool{taskId: true}
+	for _, id := range append(append([]int{}, upstream...), downstream...) {
+		inChain[id] = true
+	}
+	chain = []*Dependency{}
+	for _, edge := range edges {
+		if inChain[edge.TaskId] && inChain[edge.DependsOn] {
+			chain = append(chain, edge)
+		}
+	}
+	return upstream, downstream, chain
+}
+
+// функция упорядочивает зависимости по задаче и по задаче, которую она ждет, как они читаются из базы
+func sortDeps(edges []*Dependency) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].TaskId != edges[j].TaskId {
+			return edges[i].TaskId < edges[j].TaskId
+		}
+		return edges[i].DependsOn < edges[j].DependsOn
+	})
+}
+
+// интерфейс для чтения и в транзакции, и без нее
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// зависимости между задачами не из архива и не из корзины: такая задача никого не ждет и никого не держит
+const activeDeps = `SELECT d.task_id,d.depends_on FROM task_dependencies d
+	JOIN scheduler t ON t.id=d.task_id AND t.archived_at='' AND t.deleted_at=''
+	JOIN scheduler o ON o.id=d.depends_on AND o.archived_at='' AND o.deleted_at=''`
+
+// зависимости, из-за которых задача сейчас ждет: задача, которую она ждет, не в колонке done и это разовая задача
+// или повторяющаяся, текущее повторение которой не позже даты ждущей задачи (более ранние уже выполнены или пропущены)
+const blockingDeps = activeDeps + ` WHERE o.status<>'done' AND (o.repeat='' OR o.date<=t.date)`
+
+// все зависимости, в том числе задач из архива и корзины: они еще могут вернуться, поэтому цикл через них
+// проверяется при добавлении зависимости
+const allDeps = `SELECT d.task_id,d.depends_on FROM task_dependencies d`
+
+// функция чтения зависимостей запросом deps (activeDeps или allDeps) из SQLite или PostgreSQL
+func queryDeps(db querier, deps string) ([]*Dependency, error) {
+	rows, err := db.Query(deps + " ORDER BY d.task_id,d.depends_on")
+	if err != nil {
+		return nil, fmt.Errorf("error while query for dependencies: %w", err)
+	}
+	defer rows.Close()
+	var edges []*Dependency
+	for rows.Next() {
+		edge := Dependency{}
+		if err := rows.Scan(&edge.TaskId, &edge.DependsOn); err != nil {
+			return nil, fmt.Errorf("error while scan dependencies: %w", err)
+		}
+		edges = append(edges, &edge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	return edges, nil
+}
+
+// функция проверяет, что задача есть
+func checkTaskExists(tx *sql.Tx, id int) error {
+	var found int
+	err := tx.QueryRow("SELECT id FROM scheduler WHERE id=$1 AND archived_at='' AND deleted_at=''", id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("can't get task: %w", err)
+	}
+	return nil
+}
+
+// функция добавления зависимости в SQLite или PostgreSQL; повторное добавление ничего не меняет
+func addDependency(db *sql.DB, taskId, dependsOn int) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		for _, id := range []int{taskId, dependsOn} {
+			if err := checkTaskExists(tx, id); err != nil {
+				return err
+			}
+		}
+		edges, err := queryDeps(tx, allDeps)
+		if err != nil {
+			return err
+		}
+		if err := checkCycle(edges, taskId, dependsOn); err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO task_dependencies (task_id,depends_on) VALUES ($1,$2) ON CONFLICT DO NOTHING",
+			taskId, dependsOn)
+		if err != nil {
+			return fmt.Errorf("can't insert dependency: %w", err)
+		}
+		return nil
+	})
+}
+
+// функция удаления зависимости из SQLite или PostgreSQL
+func delDependency(db *sql.DB, taskId, dependsOn int) error {
+	res, err := db.Exec("DELETE FROM task_dependencies WHERE task_id=$1 AND depends_on=$2", taskId, dependsOn)
+	if err != nil {
+		return fmt.Errorf("can't delete dependency: %w", err)
+	}
+	if err := checkAffected(res); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrDependencyNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// функция удаления зависимостей задачи в обе стороны, например вместе с задачей
+func delTaskDeps(tx execer, taskId int) error {
+	if _, err := tx.Exec("DELETE FROM task_dependencies WHERE task_id=$1 OR depends_on=$1", taskId); err != nil {
+		return fmt.Errorf("can't delete task dependencies: %w", err)
+	}
+	return nil
+}
+
+// функция чтения задач по айди из SQLite или PostgreSQL в порядке ids
+func tasksByIds(db *sql.DB, ids []int) ([]*Task, error) {
+	tasks := make([]*Task, 0, len(ids))
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	params := make([]string, 0, len(ids))
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+		params = append(params, fmt.Sprintf("$%d", len(args)))
+	}
+	rows, err := db.Query("SELECT id,"+taskColumns+" FROM scheduler WHERE archived_at='' AND deleted_at='' AND id IN ("+strings.Join(params, ",")+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error while query for tasks: %w", err)
+	}
+	defer rows.Close()
+	byId := make(map[int]*Task, len(ids))
+	for rows.Next() {
+		task := Task{}
+		if err := rows.Scan(append([]any{&task.Id}, taskFields(&task)...)...); err != nil {
+			return nil, fmt.Errorf("error while scan tasks: %w", err)
+		}
+		byId[task.Id] = &task
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	for _, id := range ids {
+		if task := byId[id]; task != nil {
+			tasks = append(tasks, task)
+		}
+	}
+	if err := loadDetails(db, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// функция чтения цепочки зависимостей задачи из SQLite или PostgreSQL
+func taskGraph(db *sql.DB, taskId int) (*Graph, error) {
+	if found, err := tasksByIds(db, []int{taskId}); err != nil {
+		return nil, err
+	} else if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	edges, err := queryDeps(db, activeDeps)
+	if err != nil {
+		return nil, err
+	}
+	var graph Graph
+	var upstream, downstream []int
+	upstream, downstream, graph.Edges = graphIds(edges, taskId)
+	if graph.Upstream, err = tasksByIds(db, upstream); err != nil {
+		return nil, err
+	}
+	if graph.Downstream, err = tasksByIds(db, downstream); err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+// функция отмечает задачи, которые ждут выполнения других задач, одним запросом
+func loadBlocked(db *sql.DB, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byId := make(map[int]*Task, len(tasks))
+	params := make([]string, 0, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, task := range tasks {
+		byId[task.Id] = task
+		args = append(args, task.Id)
+		params = append(params, fmt.Sprintf("$%d", len(args)))
+	}
+	rows, err := db.Query("SELECT DISTINCT a.task_id FROM ("+blockingDeps+") a WHERE a.task_id IN ("+
+		strings.Join(params, ",")+")", args...)
+	if err != nil {
+		return fmt.Errorf("error while query for dependencies: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("error while scan dependencies: %w", err)
+		}
+		if task := byId[id]; task != nil {
+			task.Blocked = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("some error in cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// функция добавления исключения в SQLite или PostgreSQL; у задачи из архива или корзины исключения не меняются
+func addException(db *sql.DB, ex *Exception) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if err := checkTaskExists(tx, ex.TaskId); err != nil {
+			return err
+		}
+		return saveException(tx, ex)
+	})
+}
+
+// функция записи исключения в транзакции; повторная запись для той же даты серии заменяет прежнюю
+func saveException(tx execer, ex *Exception) error {
+	_, err := tx.Exec(`INSERT INTO exceptions (task_id,date,new_date) VALUES ($1,$2,$3)
+		ON CONFLICT (task_id,date) DO UPDATE SET new_date=excluded.new_date`, ex.TaskId, ex.Date, ex.NewDate)
+	if err != nil {
+		return fmt.Errorf("can't save exception: %w", err)
+	}
+	return nil
+}
+
+// функция удаления исключения для даты серии из SQLite или PostgreSQL
+func delException(db *sql.DB, taskId int, date string) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if err := checkTaskExists(tx, taskId); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM exceptions WHERE task_id=$1 AND date=$2", taskId, date); err != nil {
+			return fmt.Errorf("can't delete exception: %w", err)
+		}
+		return nil
+	})
+}
+
+// функция чтения всех исключений задачи из SQLite или PostgreSQL, упорядоченных по дате;
+// исключений задачи из архива или корзины не видно, как и ее самой
+func queryExceptions(db *sql.DB, taskId int) ([]*Exception, error) {
+	var exceptions []*Exception
+	err := inTx(db, func(tx *sql.Tx) error {
+		if err := checkTaskExists(tx, taskId); err != nil {
+			return err
+		}
+		rows, err := tx.Query("SELECT task_id,date,new_date FROM exceptions WHERE task_id=$1 ORDER BY date", taskId)
+		if err != nil {
+			return fmt.Errorf("error while query for exceptions: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			ex := Exception{}
+			if err := rows.Scan(&ex.TaskId, &ex.Date, &ex.NewDate); err != nil {
+				return fmt.Errorf("error while scan exceptions: %w", err)
+			}
+			exceptions = append(exceptions, &ex)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("some error in cursor: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
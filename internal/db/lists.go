@@ -0,0 +1,187 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// айди и имя списка задач по умолчанию, который создается миграцией и не удаляется
+const (
+	DefaultList     = 1
+	defaultListName = "Входящие"
+)
+
+// список задач: Дом, Работа, Покупки
+type List struct {
+	Id     int    `json:"id,string"`
+	Name   string `json:"name"`
+	Colour string `json:"colour,omitempty"` // цвет в виде #rrggbb, пустой - без цвета
+	// место списка среди других, списки упорядочены по нему и по айди
+	Position int `json:"position"`
+	// количество задач в списке, в базе не хранится
+	Count int `json:"count"`
+}
+
+// ошибки для операций со списками
+var (
+	ErrListNotFound = errors.New("list not found")
+	ErrListExists   = errors.New("list with this name already exists")
+	ErrDefaultList  = errors.New("default list can't be deleted")
+)
+
+// функция возвращает список задачи, для нулевого - список по умолчанию
+func listOrDefault(listId int) int {
+	if listId == 0 {
+		return DefaultList
+	}
+	return listId
+}
+
+// колонки списка с количеством задач для запросов SQLite и PostgreSQL
+const listQuery = `SELECT l.id,l.name,l.colour,l.position,count(s.id) FROM lists l LEFT JOIN scheduler s ON s.list_id=l.id AND s.archived_at='' AND s.deleted_at=''`
+
+// функция чтения списков с количеством задач из SQLite или PostgreSQL
+func queryLists(db *sql.DB, cond string, args ...any) ([]*List, error) {
+	rows, err := db.Query(listQuery+cond+" GROUP BY l.id,l.name,l.colour,l.position ORDER BY l.position,l.id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error while query for lists: %w", err)
+	}
+	defer rows.Close()
+	lists := []*List{}
+	for rows.Next() {
+		list := List{}
+		if err := rows.Scan(&list.Id, &list.Name, &list.Colour, &list.Position, &list.Count); err != nil {
+			return nil, fmt.Errorf("error while scan lists: %w", err)
+		}
+		lists = append(lists, &list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	return lists, nil
+}
+
+// функция чтения списка по айди из SQLite или PostgreSQL
+func getList(db *sql.DB, id int) (*List, error) {
+	lists, err := queryLists(db, " WHERE l.id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, ErrListNotFound
+	}
+	return lists[0], nil
+}
+
+// функция проверяет, что списка с таким именем, кроме списка id, нет
+func checkListName(tx *sql.Tx, name string, id int) error {
+	var other int
+	err := tx.QueryRow("SELECT id FROM lists WHERE name=$1 AND id<>$2", name, id).Scan(&other)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't check list name: %w", err)
+	}
+	return ErrListExists
+}
+
+// функция проверяет, что список есть
+func checkList(tx *sql.Tx, id int) error {
+	var found int
+	err := tx.QueryRow("SELECT id FROM lists WHERE id=$1", id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrListNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("can't get list: %w", err)
+	}
+	return nil
+}
+
+// функция добавления списка в SQLite или PostgreSQL; новый список встает после остальных
+func addList(db *sql.DB, list *List) (int64, error) {
+	var id int64
+	err := inTx(db, func(tx *sql.Tx) error {
+		if err := checkListName(tx, list.Name, 0); err != nil {
+			return err
+		}
+		err := tx.QueryRow(`INSERT INTO lists (name,colour,position)
+			SELECT $1,$2,coalesce(max(position),0)+1 FROM lists RETURNING id`, list.Name, list.Colour).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("can't insert new list: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// функция изменения имени, цвета и места списка в SQLite или PostgreSQL
+func updList(db *sql.DB, list *List) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if err := checkList(tx, list.Id); err != nil {
+			return err
+		}
+		if err := checkListName(tx, list.Name, list.Id); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE lists SET name=$1,colour=$2,position=$3 WHERE id=$4",
+			list.Name, list.Colour, list.Position, list.Id)
+		if err != nil {
+			return fmt.Errorf("can't update list: %w", err)
+		}
+		return nil
+	})
+}
+
+// функция удаления списка из SQLite или PostgreSQL: задачи переносятся в список moveTo,
+// а если он нулевой, то в корзину со временем at; окончательно их удалит очистка корзины,
+// а вернувшаяся из корзины задача попадет в список по умолчанию
+func delList(db *sql.DB, id, moveTo int, at string) error {
+	if id == DefaultList {
+		return ErrDefaultList
+	}
+	return inTx(db, func(tx *sql.Tx) error {
+		if err := checkList(tx, id); err != nil {
+			return err
+		}
+		if moveTo != 0 {
+			if err := checkList(tx, moveTo); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE scheduler SET list_id=$1 WHERE list_id=$2", moveTo, id); err != nil {
+				return fmt.Errorf("can't move tasks: %w", err)
+			}
+		} else {
+			if _, err := tx.Exec("UPDATE scheduler SET deleted_at=$1 WHERE list_id=$2 AND deleted_at=''", at, id); err != nil {
+				return fmt.Errorf("can't trash list tasks: %w", err)
+			}
+			if _, err := tx.Exec("UPDATE scheduler SET list_id=$1 WHERE list_id=$2", DefaultList, id); err != nil {
+				return fmt.Errorf("can't move tasks: %w", err)
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM lists WHERE id=$1", id); err != nil {
+			return fmt.Errorf("can't delete list: %w", err)
+		}
+		return nil
+	})
+}
+
+// функция переноса задачи в другой список в SQLite или PostgreSQL
+func moveTask(db *sql.DB, taskId, listId int) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if err := checkList(tx, listId); err != nil {
+			return err
+		}
+		res, err := tx.Exec("UPDATE scheduler SET list_id=$1 WHERE id=$2 AND archived_at='' AND deleted_at=''", listId, taskId)
+		if err != nil {
+			return fmt.Errorf("can't move task: %w", err)
+		}
+		return checkAffected(res)
+	})
+}
@@ -0,0 +1,940 @@
+// пакет для работы с БД
+package db
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// хранилище задач в памяти процесса, данные пропадают при перезапуске; подходит для тестов
+type MemoryStore struct {
+	mu         sync.Mutex
+	lastId     int
+	tasks      map[int]Task
+	exceptions map[int]map[string]string // айди задачи -> исходная дата -> новая дата
+	lastListId int
+	lists      map[int]List
+	lastItemId int
+	items      map[int]ChecklistItem
+	deps       map[Dependency]bool
+	lastDoneId int
+	done       map[int]Completion
+}
+
+// функция создания пустого хранилища в памяти
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		tasks:      make(map[int]Task),
+		exceptions: make(map[int]map[string]string),
+		lastListId: DefaultList,
+		lists:      map[int]List{DefaultList: {Id: DefaultList, Name: defaultListName}},
+		items:      make(map[int]ChecklistItem),
+		deps:       make(map[Dependency]bool),
+		done:       make(map[int]Completion),
+	}
+}
+
+// функция закрытия хранилища, в памяти закрывать нечего
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// функция добавления новой записи
+func (s *MemoryStore) AddTask(task *Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastId++
+	stored := *task
+	stored.Id = s.lastId
+	stored.ListId = listOrDefault(task.ListId)
+	stored.Priority, stored.Status = priorityOrDefault(task.Priority), statusOrDefault(task.Status)
+	stored.Tags = slices.Clone(task.Tags)
+	// вычисляемые поля не хранятся
+	stored.Due, stored.TitleHighlight, stored.Snippet, stored.Progress, stored.Blocked = "", "", "", nil, false
+	s.tasks[stored.Id] = stored
+	return int64(stored.Id), nil
+}
+
+// функция чтения страницы списка задач с порядком, фильтрами и курсором
+func (s *MemoryStore) ListTasks(q TaskQuery) (*TaskPage, error) {
+	_, c, err := prepareQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	match := func(Task) bool { return true }
+	var expr *searchExpr
+	if q.Search != "" {
+		if expr, err = parseSearch(q.Search); err != nil {
+			return nil, err
+		}
+		match = func(task Task) bool { return expr.match(&task) }
+	}
+	tasks := s.filter(math.MaxInt, func(task Task) bool {
+		return match(task) &&
+			task.Deleted == "" &&
+			(task.Archived != "") == q.Archived &&
+			(q.From == "" || task.Date >= q.From) &&
+			(q.To == "" || task.Date <= q.To) &&
+			(q.HasRepeat == nil || *q.HasRepeat == (task.Repeat != "")) &&
+			hasTags(&task, q.Tags) &&
+			(q.ListId == 0 || task.ListId == q.ListId) &&
+			(q.Status == "" || task.Status == q.Status) &&
+			(q.Blocked == nil || *q.Blocked == s.blocked(task.Id))
+	})
+	// в памяти нет оценки релевантности, поэтому найденные задачи идут по дате
+	for _, task := range tasks {
+		task.rank = task.Date + task.DueTime
+		if expr != nil && expr.positive() {
+			expr.highlight(task)
+		}
+	}
+	// сравнение задачи с ключом порядка так же, как кортежей в SQL
+	less := func(a *Task, keys []string, id int) bool {
+		ak := sortKeys(a, q.order())
+		for i := range ak {
+			if ak[i] != keys[i] {
+				return ak[i] < keys[i]
+			}
+		}
+		return a.Id < id
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if q.Desc {
+			i, j = j, i
+		}
+		return less(tasks[i], sortKeys(tasks[j], q.order()), tasks[j].Id)
+	})
+	total := len(tasks)
+	page := make([]*Task, 0, q.Limit+1)
+	for _, task := range tasks {
+		// задачи до курсора и сама задача из курсора уже были на прошлых страницах
+		if c != nil && (task.Id == c.Id || less(task, c.Keys, c.Id) != q.Desc) {
+			continue
+		}
+		page = append(page, task)
+		if len(page) > q.Limit {
+			break
+		}
+	}
+	return makePage(q, page, total), nil
+}
+
+// функция проверяет, что у задачи есть все метки
+func hasTags(task *Task, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(task.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// функция возвращает копии подходящих записей, упорядоченные по дате и времени
+func (s *MemoryStore) filter(limit int, match func(Task) bool) []*Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks := []*Task{}
+	for _, task := range s.tasks {
+		if match(task) {
+			task.Progress, task.Blocked = s.progress(task.Id), s.blocked(task.Id)
+			tasks = append(tasks, &task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if ar, br := priorityRank(a.Priority), priorityRank(b.Priority); ar != br {
+			return ar < br
+		}
+		if a.DueTime != b.DueTime {
+			return a.DueTime < b.DueTime
+		}
+		return a.Id < b.Id
+	})
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks
+}
+
+// функция запроса записи по айди
+func (s *MemoryStore) GetTask(id string) (*Task, error) {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.active(taskId)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	task.Progress, task.Blocked = s.progress(taskId), s.blocked(taskId)
+	return &task, nil
+}
+
+// функция изменения всех полей записи по айди
+func (s *MemoryStore) UpdTask(task *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.active(task.Id)
+	if !ok {
+		return ErrNotFound
+	}
+	stored := *task
+	stored.ListId = listOrDefault(task.ListId)
+	stored.Priority, stored.Status = priorityOrDefault(task.Priority), statusOrDefault(task.Status)
+	stored.Tags = slices.Clone(task.Tags)
+	// вычисляемые поля не хранятся
+	stored.Due, stored.TitleHighlight, stored.Snippet, stored.Progress, stored.Blocked = "", "", "", nil, false
+	// время создания, переноса в архив и в корзину при изменении не меняются
+	stored.Created, stored.Archived, stored.Deleted = old.Created, old.Archived, old.Deleted
+	s.tasks[task.Id] = stored
+	return nil
+}
+
+// функция изменения поля с датой записи и количества оставшихся повторений
+func (s *MemoryStore) UpDateTask(next string, remaining int, id string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.active(taskId)
+	if !ok {
+		return ErrNotFound
+	}
+	task.Date = next
+	task.Remaining = remaining
+	s.tasks[taskId] = task
+	return nil
+}
+
+// функция окончательного удаления записи, ее исключений, чек-листа, зависимостей и выполнений по айди
+func (s *MemoryStore) DelTask(id string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[taskId]; !ok {
+		return ErrNotFound
+	}
+	delete(s.tasks, taskId)
+	delete(s.exceptions, taskId)
+	s.delTaskChecklist(taskId)
+	s.delTaskDeps(taskId)
+	s.delTaskCompletions(taskId)
+	return nil
+}
+
+// функция добавления исключения; повторная запись для той же даты серии заменяет прежнюю
+func (s *MemoryStore) AddException(ex *Exception) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active(ex.TaskId); !ok {
+		return ErrNotFound
+	}
+	if s.exceptions[ex.TaskId] == nil {
+		s.exceptions[ex.TaskId] = make(map[string]string)
+	}
+	s.exceptions[ex.TaskId][ex.Date] = ex.NewDate
+	return nil
+}
+
+// функция удаления исключения для даты серии
+func (s *MemoryStore) DelException(taskId int, date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active(taskId); !ok {
+		return ErrNotFound
+	}
+	delete(s.exceptions[taskId], date)
+	return nil
+}
+
+// функция чтения всех исключений задачи, упорядоченных по дате
+func (s *MemoryStore) Exceptions(taskId int) ([]*Exception, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active(taskId); !ok {
+		return nil, ErrNotFound
+	}
+	var exceptions []*Exception
+	for date, newDate := range s.exceptions[taskId] {
+		exceptions = append(exceptions, &Exception{TaskId: taskId, Date: date, NewDate: newDate})
+	}
+	sort.Slice(exceptions, func(i, j int) bool { return exceptions[i].Date < exceptions[j].Date })
+	return exceptions, nil
+}
+
+// функция чтения всех меток с количеством задач, сначала самые частые
+func (s *MemoryStore) Tags() ([]*Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]int)
+	for _, task := range s.tasks {
+		if task.Archived != "" || task.Deleted != "" {
+			continue
+		}
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]*Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, &Tag{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// функция замены метки у всех задач; replace возвращает новые метки задачи с меткой name
+func (s *MemoryStore) replaceTag(name string, replace func(tags []string) []string) error {
+	found := false
+	for id, task := range s.tasks {
+		if !slices.Contains(task.Tags, name) {
+			continue
+		}
+		found = true
+		task.Tags = replace(slices.Clone(task.Tags))
+		sort.Strings(task.Tags)
+		s.tasks[id] = task
+	}
+	if !found {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// функция возвращает метку с количеством задач не из архива и не из корзины, как в Tags
+func (s *MemoryStore) countTag(name string) *Tag {
+	tag := Tag{Name: name}
+	for _, task := range s.tasks {
+		if task.Archived == "" && task.Deleted == "" && slices.Contains(task.Tags, name) {
+			tag.Count++
+		}
+	}
+	return &tag
+}
+
+// функция переименования метки у всех задач; переименование в то же имя ничего не меняет
+func (s *MemoryStore) RenameTag(name, newName string) (*Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found, exists bool
+	for _, task := range s.tasks {
+		found = found || slices.Contains(task.Tags, name)
+		exists = exists || slices.Contains(task.Tags, newName)
+	}
+	if found && exists && name != newName {
+		return nil, ErrTagExists
+	}
+	err := s.replaceTag(name, func(tags []string) []string {
+		tags[slices.Index(tags, name)] = newName
+		return tags
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.countTag(newName), nil
+}
+
+// функция переноса задач с одной метки на другую
+func (s *MemoryStore) MergeTag(name, into string) (*Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.replaceTag(name, func(tags []string) []string {
+		if name == into {
+			return tags
+		}
+		tags = slices.DeleteFunc(tags, func(tag string) bool { return tag == name })
+		if !slices.Contains(tags, into) {
+			tags = append(tags, into)
+		}
+		return tags
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.countTag(into), nil
+}
+
+// функция удаления метки у всех задач
+func (s *MemoryStore) DelTag(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replaceTag(name, func(tags []string) []string {
+		return slices.DeleteFunc(tags, func(tag string) bool { return tag == name })
+	})
+}
+
+// функция возвращает копию списка с количеством задач
+func (s *MemoryStore) countList(list List) *List {
+	list.Count = 0
+	for _, task := range s.tasks {
+		if task.ListId == list.Id && task.Archived == "" && task.Deleted == "" {
+			list.Count++
+		}
+	}
+	return &list
+}
+
+// функция проверяет, что имя списка не занято другим списком
+func (s *MemoryStore) checkListName(name string, id int) error {
+	for _, list := range s.lists {
+		if list.Name == name && list.Id != id {
+			return ErrListExists
+		}
+	}
+	return nil
+}
+
+// функция чтения всех списков с количеством задач
+func (s *MemoryStore) Lists() ([]*List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lists := make([]*List, 0, len(s.lists))
+	for _, list := range s.lists {
+		lists = append(lists, s.countList(list))
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		if lists[i].Position != lists[j].Position {
+			return lists[i].Position < lists[j].Position
+		}
+		return lists[i].Id < lists[j].Id
+	})
+	return lists, nil
+}
+
+// функция чтения списка по айди
+func (s *MemoryStore) GetList(id int) (*List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, ok := s.lists[id]
+	if !ok {
+		return nil, ErrListNotFound
+	}
+	return s.countList(list), nil
+}
+
+// функция добавления списка после остальных
+func (s *MemoryStore) AddList(list *List) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkListName(list.Name, 0); err != nil {
+		return 0, err
+	}
+	position := 0
+	for _, other := range s.lists {
+		position = max(position, other.Position)
+	}
+	s.lastListId++
+	s.lists[s.lastListId] = List{Id: s.lastListId, Name: list.Name, Colour: list.Colour, Position: position + 1}
+	return int64(s.lastListId), nil
+}
+
+// функция изменения имени, цвета и места списка
+func (s *MemoryStore) UpdList(list *List) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lists[list.Id]; !ok {
+		return ErrListNotFound
+	}
+	if err := s.checkListName(list.Name, list.Id); err != nil {
+		return err
+	}
+	s.lists[list.Id] = List{Id: list.Id, Name: list.Name, Colour: list.Colour, Position: list.Position}
+	return nil
+}
+
+// функция удаления списка с переносом его задач в другой список или в корзину
+func (s *MemoryStore) DelList(id, moveTo int, at string) error {
+	if id == DefaultList {
+		return ErrDefaultList
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lists[id]; !ok {
+		return ErrListNotFound
+	}
+	if _, ok := s.lists[moveTo]; moveTo != 0 && !ok {
+		return ErrListNotFound
+	}
+	for taskId, task := range s.tasks {
+		switch {
+		case task.ListId != id:
+		case moveTo != 0:
+			task.ListId = moveTo
+			s.tasks[taskId] = task
+		default:
+			// задача уходит в корзину, а вернется в список по умолчанию
+			if task.Deleted == "" {
+				task.Deleted = at
+			}
+			task.ListId = DefaultList
+			s.tasks[taskId] = task
+		}
+	}
+	delete(s.lists, id)
+	return nil
+}
+
+// функция переноса задачи в другой список
+func (s *MemoryStore) MoveTask(id string, listId int) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lists[listId]; !ok {
+		return ErrListNotFound
+	}
+	task, ok := s.active(taskId)
+	if !ok {
+		return ErrNotFound
+	}
+	task.ListId = listId
+	s.tasks[taskId] = task
+	return nil
+}
+
+// функция изменения статуса задачи
+func (s *MemoryStore) SetStatus(id string, status string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.active(taskId)
+	if !ok {
+		return ErrNotFound
+	}
+	task.Status = statusOrDefault(status)
+	s.tasks[taskId] = task
+	return nil
+}
+
+// функция возвращает прогресс чек-листа задачи или nil, если пунктов нет
+func (s *MemoryStore) progress(taskId int) *Progress {
+	var done, total int
+	for _, item := range s.items {
+		if item.TaskId != taskId {
+			continue
+		}
+		total++
+		if item.Done {
+			done++
+		}
+	}
+	return newProgress(done, total)
+}
+
+// функция удаления чек-листа задачи
+func (s *MemoryStore) delTaskChecklist(taskId int) {
+	for id, item := range s.items {
+		if item.TaskId == taskId {
+			delete(s.items, id)
+		}
+	}
+}
+
+// функция чтения пунктов чек-листа задачи: сначала верхнего уровня, затем вложенные
+func (s *MemoryStore) ChecklistItems(taskId int) ([]*ChecklistItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := []*ChecklistItem{}
+	for _, item := range s.items {
+		if item.TaskId == taskId {
+			items = append(items, &item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.ParentId != b.ParentId {
+			return a.ParentId < b.ParentId
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.Id < b.Id
+	})
+	return items, nil
+}
+
+// функция добавления пункта чек-листа после пунктов того же уровня
+func (s *MemoryStore) AddChecklistItem(item *ChecklistItem) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active(item.TaskId); !ok {
+		return 0, ErrNotFound
+	}
+	if parent, ok := s.items[item.ParentId]; item.ParentId != 0 && (!ok || parent.TaskId != item.TaskId) {
+		return 0, ErrItemNotFound
+	}
+	position := 0
+	for _, other := range s.items {
+		if other.TaskId == item.TaskId && other.ParentId == item.ParentId {
+			position = max(position, other.Position)
+		}
+	}
+	s.lastItemId++
+	s.items[s.lastItemId] = ChecklistItem{Id: s.lastItemId, TaskId: item.TaskId, ParentId: item.ParentId,
+		Text: item.Text, Done: item.Done, Position: position + 1}
+	return int64(s.lastItemId), nil
+}
+
+// функция изменения текста, отметки и места пункта чек-листа
+func (s *MemoryStore) UpdChecklistItem(item *ChecklistItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.items[item.Id]
+	if !ok || stored.TaskId != item.TaskId {
+		return ErrItemNotFound
+	}
+	stored.Text, stored.Done, stored.Position = item.Text, item.Done, item.Position
+	s.items[item.Id] = stored
+	return nil
+}
+
+// функция удаления пункта чек-листа вместе с вложенными в него
+func (s *MemoryStore) DelChecklistItem(taskId, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item, ok := s.items[id]; !ok || item.TaskId != taskId {
+		return ErrItemNotFound
+	}
+	for parents := []int{id}; len(parents) > 0; parents = parents[1:] {
+		for childId, item := range s.items {
+			if item.ParentId == parents[0] {
+				parents = append(parents, childId)
+			}
+		}
+		delete(s.items, parents[0])
+	}
+	return nil
+}
+
+// функция проверяет, что задача ждет выполнения других задач, как blockingDeps
+func (s *MemoryStore) blocked(taskId int) bool {
+	for dep := range s.deps {
+		if dep.TaskId != taskId || !s.activeDep(dep) {
+			continue
+		}
+		task, other := s.tasks[dep.TaskId], s.tasks[dep.DependsOn]
+		if other.Status != StatusDone && (other.Repeat == "" || other.Date <= task.Date) {
+			return true
+		}
+	}
+	return false
+}
+
+// функция проверяет, что зависимость связывает задачи не из архива и не из корзины
+func (s *MemoryStore) activeDep(dep Dependency) bool {
+	_, from := s.active(dep.TaskId)
+	_, to := s.active(dep.DependsOn)
+	return from && to
+}
+
+// функция возвращает все зависимости между задачами не из архива и не из корзины по порядку
+func (s *MemoryStore) edges() []*Dependency {
+	edges := make([]*Dependency, 0, len(s.deps))
+	for dep := range s.deps {
+		if s.activeDep(dep) {
+			edges = append(edges, &dep)
+		}
+	}
+	sortDeps(edges)
+	return edges
+}
+
+// функция возвращает все зависимости по порядку, в том числе задач из архива и корзины
+func (s *MemoryStore) allEdges() []*Dependency {
+	edges := make([]*Dependency, 0, len(s.deps))
+	for dep := range s.deps {
+		edges = append(edges, &dep)
+	}
+	sortDeps(edges)
+	return edges
+}
+
+// функция удаления зависимостей задачи в обе стороны
+func (s *MemoryStore) delTaskDeps(taskId int) {
+	for dep := range s.deps {
+		if dep.TaskId == taskId || dep.DependsOn == taskId {
+			delete(s.deps, dep)
+		}
+	}
+}
+
+// функция добавления зависимости задачи; повторное добавление ничего не меняет
+func (s *MemoryStore) AddDependency(taskId, dependsOn int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range []int{taskId, dependsOn} {
+		if _, ok := s.active(id); !ok {
+			return ErrNotFound
+		}
+	}
+	// цикл проверяем и через задачи из архива и корзины, как allDeps
+	if err := checkCycle(s.allEdges(), taskId, dependsOn); err != nil {
+		return err
+	}
+	s.deps[Dependency{TaskId: taskId, DependsOn: dependsOn}] = true
+	return nil
+}
+
+// функция удаления зависимости задачи
+func (s *MemoryStore) DelDependency(taskId, dependsOn int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dep := Dependency{TaskId: taskId, DependsOn: dependsOn}
+	if !s.deps[dep] {
+		return ErrDependencyNotFound
+	}
+	delete(s.deps, dep)
+	return nil
+}
+
+// функция чтения цепочки зависимостей задачи
+func (s *MemoryStore) TaskGraph(taskId int) (*Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active(taskId); !ok {
+		return nil, ErrNotFound
+	}
+	upstream, downstream, edges := graphIds(s.edges(), taskId)
+	graph := Graph{Upstream: []*Task{}, Downstream: []*Task{}, Edges: edges}
+	for _, id := range upstream {
+		graph.Upstream = append(graph.Upstream, s.graphTask(id))
+	}
+	for _, id := range downstream {
+		graph.Downstream = append(graph.Downstream, s.graphTask(id))
+	}
+	return &graph, nil
+}
+
+// функция возвращает копию задачи с вычисляемыми полями
+func (s *MemoryStore) graphTask(id int) *Task {
+	task := s.tasks[id]
+	task.Progress, task.Blocked = s.progress(id), s.blocked(id)
+	return &task
+}
+
+// функция возвращает задачу, если она есть и не в корзине
+func (s *MemoryStore) live(id int) (Task, bool) {
+	task, ok := s.tasks[id]
+	return task, ok && task.Deleted == ""
+}
+
+// функция возвращает задачу, если она есть и не в архиве и не в корзине
+func (s *MemoryStore) active(id int) (Task, bool) {
+	task, ok := s.live(id)
+	return task, ok && task.Archived == ""
+}
+
+// функция перехода задачи на следующее повторение или в архив вместе с записью выполнения
+func (s *MemoryStore) AdvanceTask(a *Advance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.active(a.TaskId)
+	if !ok {
+		return ErrNotFound
+	}
+	if a.Exception != "" {
+		if s.exceptions[a.TaskId] == nil {
+			s.exceptions[a.TaskId] = make(map[string]string)
+		}
+		s.exceptions[a.TaskId][a.Exception] = ""
+	}
+	if a.Completion != nil {
+		// отметки запоминаем до того, как новое повторение их снимет
+		c := *a.Completion
+		c.Checked = []int{}
+		for id, item := range s.items {
+			if item.TaskId == a.TaskId && item.Done {
+				c.Checked = append(c.Checked, id)
+			}
+		}
+		sort.Ints(c.Checked)
+		s.lastDoneId++
+		c.Id, c.Title = s.lastDoneId, ""
+		s.done[c.Id] = c
+	}
+	if a.Archived != "" {
+		task.Archived = a.Archived
+		s.tasks[a.TaskId] = task
+		return nil
+	}
+	task.Date, task.Remaining, task.Status = a.Date, a.Remaining, StatusTodo
+	s.tasks[a.TaskId] = task
+	for id, item := range s.items {
+		if item.TaskId == a.TaskId {
+			item.Done = false
+			s.items[id] = item
+		}
+	}
+	return nil
+}
+
+// функция чтения выполнений с датами повторений от from до to включительно, сначала последние
+func (s *MemoryStore) Completions(from, to string, limit int) ([]*Completion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	completions := []*Completion{}
+	for _, c := range s.done {
+		task, ok := s.live(c.TaskId)
+		if !ok || (from != "" && c.Date < from) || (to != "" && c.Date > to) {
+			continue
+		}
+		c.Title = task.Title
+		completions = append(completions, &c)
+	}
+	sort.Slice(completions, func(i, j int) bool { return completions[i].Id > completions[j].Id })
+	if len(completions) > limit {
+		completions = completions[:limit]
+	}
+	return completions, nil
+}
+
+// функция чтения последнего выполнения
+func (s *MemoryStore) LastCompletion() (*Completion, error) {
+	completions, err := s.Completions("", "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(completions) == 0 {
+		return nil, ErrCompletionNotFound
+	}
+	return completions[0], nil
+}
+
+// функция отмены выполнения: задача возвращается на дату повторения с прежними колонкой и отметками чек-листа
+// и уходит из архива
+func (s *MemoryStore) UndoCompletion(c *Completion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.done[c.Id]; !ok {
+		return ErrCompletionNotFound
+	}
+	task, ok := s.live(c.TaskId)
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.done, c.Id)
+	task.Date, task.Remaining, task.Archived = c.Date, c.Remaining, ""
+	// при выполнении перенесенное повторение стало обычным исключением, возвращаем перенос
+	if c.Origin != c.Date {
+		if s.exceptions[c.TaskId] == nil {
+			s.exceptions[c.TaskId] = make(map[string]string)
+		}
+		s.exceptions[c.TaskId][c.Origin] = c.Date
+	}
+	if c.Status != "" {
+		task.Status = c.Status
+		checked := make(map[int]bool, len(c.Checked))
+		for _, id := range c.Checked {
+			checked[id] = true
+		}
+		for id, item := range s.items {
+			if item.TaskId == c.TaskId {
+				item.Done = checked[id]
+				s.items[id] = item
+			}
+		}
+	}
+	s.tasks[c.TaskId] = task
+	return nil
+}
+
+// функция удаления выполнений задачи
+func (s *MemoryStore) delTaskCompletions(taskId int) {
+	for id, c := range s.done {
+		if c.TaskId == taskId {
+			delete(s.done, id)
+		}
+	}
+}
+
+// функция переноса задачи в корзину
+func (s *MemoryStore) TrashTask(id string, at string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.live(taskId)
+	if !ok {
+		return ErrNotFound
+	}
+	task.Deleted = at
+	s.tasks[taskId] = task
+	return nil
+}
+
+// функция чтения задач из корзины, сначала последние удаленные
+func (s *MemoryStore) Trash(limit int) ([]*Task, error) {
+	tasks := s.filter(math.MaxInt, func(task Task) bool { return task.Deleted != "" })
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Deleted != tasks[j].Deleted {
+			return tasks[i].Deleted > tasks[j].Deleted
+		}
+		return tasks[i].Id > tasks[j].Id
+	})
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks, nil
+}
+
+// функция возврата задачи из корзины
+func (s *MemoryStore) RestoreTask(id string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[taskId]
+	if !ok || task.Deleted == "" {
+		return ErrNotFound
+	}
+	task.Deleted = ""
+	s.tasks[taskId] = task
+	return nil
+}
+
+// функция окончательного удаления задач, попавших в корзину раньше before
+func (s *MemoryStore) PurgeTrash(before string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for taskId, task := range s.tasks {
+		if task.Deleted == "" || task.Deleted >= before {
+			continue
+		}
+		delete(s.tasks, taskId)
+		delete(s.exceptions, taskId)
+		s.delTaskChecklist(taskId)
+		s.delTaskDeps(taskId)
+		s.delTaskCompletions(taskId)
+		purged++
+	}
+	return purged, nil
+}
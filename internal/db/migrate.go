@@ -0,0 +1,264 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// шаги изменения схемы для каждого вида БД: файлы migrations/вид/NNNN_название.up.sql и NNNN_название.down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// применение шагов изменения схемы к БД
+type migrator struct {
+	db         *sql.DB
+	dir        string              // директория с шагами в migrationFiles
+	tableQuery string              // запрос количества таблиц с именем $1
+	legacy     func() (int, error) // версия схемы базы, созданной до появления миграций, может быть nil
+}
+
+// шаг изменения схемы БД
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// состояние шага изменения схемы в БД
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt string // время применения в формате RFC 3339
+}
+
+// функция чтения встроенных шагов изменения схемы, упорядоченных по версии
+func (m *migrator) migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, m.dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		name := path.Base(file)
+		// 0002_add_remaining.up.sql -> 2, add_remaining, up
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		verStr, title, ok2 := strings.Cut(base, "_")
+		version, err := strconv.Atoi(verStr)
+		if !ok || !ok2 || err != nil || version < 1 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("wrong migration file name %s", name)
+		}
+		data, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("can't read migration %s: %w", name, err)
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: title}
+			byVersion[version] = mig
+		}
+		if mig.Name != title {
+			return nil, fmt.Errorf("migration %d has different names %s and %s", version, mig.Name, title)
+		}
+		if direction == "up" {
+			mig.up = string(data)
+		} else {
+			mig.down = string(data)
+		}
+	}
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.up == "" || mig.down == "" {
+			return nil, fmt.Errorf("migration %d must have up and down steps", mig.Version)
+		}
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i, mig := range list {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+	return list, nil
+}
+
+// функция создания таблицы версий схемы; базы, созданные до появления миграций,
+// получают версию, соответствующую уже существующим таблицам и колонкам
+func (m *migrator) initMigrations() error {
+	exists, err := m.tableExists("schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+	version := 0
+	if m.legacy != nil {
+		if version, err = m.legacy(); err != nil {
+			return err
+		}
+	}
+	list, err := m.migrations()
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(128) NOT NULL DEFAULT '',
+		applied_at VARCHAR(32) NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return fmt.Errorf("error while creating table schema_migrations: %w", err)
+	}
+	for _, mig := range list[:version] {
+		if err := markApplied(tx, mig); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// функция проверки наличия таблицы
+func (m *migrator) tableExists(table string) (bool, error) {
+	var n int
+	err := m.db.QueryRow(m.tableQuery, table).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("can't check table %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+// функция записи примененного шага в таблицу версий
+func markApplied(tx *sql.Tx, mig Migration) error {
+	_, err := tx.Exec("INSERT INTO schema_migrations (version,name,applied_at) VALUES ($1,$2,$3)",
+		mig.Version, mig.Name, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("can't save migration %d: %w", mig.Version, err)
+	}
+	return nil
+}
+
+// функция возвращает текущую версию схемы БД
+func (m *migrator) SchemaVersion() (int, error) {
+	if err := m.initMigrations(); err != nil {
+		return 0, err
+	}
+	var version int
+	if err := m.db.QueryRow("SELECT coalesce(max(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("can't read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// функция возвращает все шаги изменения схемы с отметкой, применены ли они
+func (m *migrator) MigrationStatus() ([]MigrationState, error) {
+	list, err := m.migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.initMigrations(); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]string)
+	rows, err := m.db.Query("SELECT version,applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("can't read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("can't scan schema_migrations: %w", err)
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+
+	states := make([]MigrationState, 0, len(list))
+	for _, mig := range list {
+		at, ok := applied[mig.Version]
+		states = append(states, MigrationState{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+	return states, nil
+}
+
+// функция применения всех шагов, которых еще нет в БД; каждый шаг выполняется в своей транзакции
+func (m *migrator) Migrate() error {
+	list, err := m.migrations()
+	if err != nil {
+		return err
+	}
+	version, err := m.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > len(list) {
+		return fmt.Errorf("database schema version %d is newer than the application knows (%d)", version, len(list))
+	}
+	for _, mig := range list[version:] {
+		if err := m.apply(mig.up, func(tx *sql.Tx) error { return markApplied(tx, mig) }); err != nil {
+			return fmt.Errorf("can't apply migration %d %s: %w", mig.Version, mig.Name, err)
+		}
+	}
+	return nil
+}
+
+// функция отката последних steps шагов изменения схемы, каждый в своей транзакции
+func (m *migrator) Rollback(steps int) error {
+	list, err := m.migrations()
+	if err != nil {
+		return err
+	}
+	version, err := m.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > len(list) {
+		return fmt.Errorf("database schema version %d is newer than the application knows (%d)", version, len(list))
+	}
+	if steps > version {
+		return fmt.Errorf("can't roll back %d steps, schema version is %d", steps, version)
+	}
+	for i := 0; i < steps; i++ {
+		mig := list[version-1-i]
+		err := m.apply(mig.down, func(tx *sql.Tx) error {
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version=$1", mig.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("can't roll back migration %d %s: %w", mig.Version, mig.Name, err)
+		}
+	}
+	return nil
+}
+
+// функция выполнения шага и записи о нем в одной транзакции
+func (m *migrator) apply(query string, record func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,8 @@
+CREATE TABLE scheduler (
+	id SERIAL PRIMARY KEY,
+	date VARCHAR(8) NOT NULL DEFAULT '',
+	title VARCHAR(256) NOT NULL DEFAULT 'задача',
+	comment TEXT NOT NULL DEFAULT '',
+	repeat VARCHAR(128) NOT NULL DEFAULT ''
+);
+CREATE INDEX date_scheduler ON scheduler (date);
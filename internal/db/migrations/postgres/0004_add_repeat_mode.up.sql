@@ -0,0 +1 @@
+ALTER TABLE scheduler ADD COLUMN repeat_mode VARCHAR(16) NOT NULL DEFAULT 'fixed';
@@ -0,0 +1,2 @@
+DROP INDEX created_scheduler;
+ALTER TABLE scheduler DROP COLUMN created_at;
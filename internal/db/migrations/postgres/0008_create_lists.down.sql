@@ -0,0 +1,3 @@
+DROP INDEX list_scheduler;
+ALTER TABLE scheduler DROP COLUMN list_id;
+DROP TABLE lists;
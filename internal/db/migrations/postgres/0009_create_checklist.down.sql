@@ -0,0 +1,2 @@
+DROP INDEX task_checklist_items;
+DROP TABLE checklist_items;
@@ -0,0 +1,6 @@
+CREATE TABLE task_dependencies (
+	task_id INTEGER NOT NULL,
+	depends_on INTEGER NOT NULL,
+	PRIMARY KEY (task_id, depends_on)
+);
+CREATE INDEX depends_on_task_dependencies ON task_dependencies (depends_on);
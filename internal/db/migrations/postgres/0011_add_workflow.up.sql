@@ -0,0 +1,4 @@
+ALTER TABLE scheduler ADD COLUMN priority VARCHAR(8) NOT NULL DEFAULT 'normal';
+ALTER TABLE scheduler ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'todo';
+ALTER TABLE scheduler ADD COLUMN estimate INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX status_scheduler ON scheduler (status);
@@ -0,0 +1,5 @@
+DROP INDEX date_completions;
+DROP INDEX task_completions;
+DROP TABLE completions;
+DROP INDEX archived_scheduler;
+ALTER TABLE scheduler DROP COLUMN archived_at;
@@ -0,0 +1,2 @@
+ALTER TABLE scheduler ADD COLUMN deleted_at VARCHAR(32) NOT NULL DEFAULT '';
+CREATE INDEX deleted_scheduler ON scheduler (deleted_at);
@@ -0,0 +1,2 @@
+ALTER TABLE completions ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE completions ADD COLUMN checked TEXT NOT NULL DEFAULT '';
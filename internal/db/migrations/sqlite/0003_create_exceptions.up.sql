@@ -0,0 +1,6 @@
+CREATE TABLE exceptions (
+	task_id INTEGER NOT NULL,
+	date CHAR(8) NOT NULL,
+	new_date CHAR(8) NOT NULL DEFAULT "",
+	PRIMARY KEY (task_id, date)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE scheduler DROP COLUMN timezone;
+ALTER TABLE scheduler DROP COLUMN due_time;
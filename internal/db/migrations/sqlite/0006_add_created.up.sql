@@ -0,0 +1,2 @@
+ALTER TABLE scheduler ADD COLUMN created_at VARCHAR(32) NOT NULL DEFAULT "";
+CREATE INDEX created_scheduler ON scheduler (created_at);
@@ -0,0 +1,9 @@
+CREATE TABLE lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(64) NOT NULL UNIQUE,
+	colour VARCHAR(7) NOT NULL DEFAULT "",
+	position INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO lists (id, name) VALUES (1, 'Входящие');
+ALTER TABLE scheduler ADD COLUMN list_id INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX list_scheduler ON scheduler (list_id);
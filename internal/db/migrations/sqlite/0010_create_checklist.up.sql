@@ -0,0 +1,9 @@
+CREATE TABLE checklist_items (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	parent_id INTEGER NOT NULL DEFAULT 0,
+	text VARCHAR(256) NOT NULL DEFAULT "",
+	done BOOLEAN NOT NULL DEFAULT 0,
+	position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX task_checklist_items ON checklist_items (task_id);
@@ -0,0 +1,2 @@
+DROP INDEX depends_on_task_dependencies;
+DROP TABLE task_dependencies;
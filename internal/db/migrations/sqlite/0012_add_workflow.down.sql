@@ -0,0 +1,4 @@
+DROP INDEX status_scheduler;
+ALTER TABLE scheduler DROP COLUMN estimate;
+ALTER TABLE scheduler DROP COLUMN status;
+ALTER TABLE scheduler DROP COLUMN priority;
@@ -0,0 +1,12 @@
+ALTER TABLE scheduler ADD COLUMN archived_at VARCHAR(32) NOT NULL DEFAULT "";
+CREATE INDEX archived_scheduler ON scheduler (archived_at);
+CREATE TABLE completions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	date VARCHAR(8) NOT NULL DEFAULT "",
+	origin VARCHAR(8) NOT NULL DEFAULT "",
+	remaining INTEGER NOT NULL DEFAULT 0,
+	completed_at VARCHAR(32) NOT NULL DEFAULT ""
+);
+CREATE INDEX task_completions ON completions (task_id);
+CREATE INDEX date_completions ON completions (date);
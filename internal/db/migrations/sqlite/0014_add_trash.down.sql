@@ -0,0 +1,2 @@
+DROP INDEX deleted_scheduler;
+ALTER TABLE scheduler DROP COLUMN deleted_at;
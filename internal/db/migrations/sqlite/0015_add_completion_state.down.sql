@@ -0,0 +1,2 @@
+ALTER TABLE completions DROP COLUMN checked;
+ALTER TABLE completions DROP COLUMN status;
@@ -0,0 +1,320 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// поля, по которым можно упорядочить список задач
+const (
+	SortDate    = "date"
+	SortTitle   = "title"
+	SortId      = "id"
+	SortCreated = "created"
+	SortRank    = "rank" // по релевантности найденных задач, для остальных - по дате
+)
+
+// ошибка для курсора, который не удалось разобрать или который выдан для другого порядка
+var ErrBadCursor = errors.New("wrong cursor")
+
+// параметры выборки списка задач
+type TaskQuery struct {
+	Limit  int
+	Cursor string // курсор из TaskPage.NextCursor предыдущей страницы, пустой - первая страница
+	Sort   string // одно из Sort..., пустое - SortRank при поиске по словам, иначе SortDate
+	Desc   bool   // обратный порядок
+	// строка поиска: слова, условия на поля и даты, см. parseSearch
+	Search string
+	// диапазон дат задач в формате 20060102 включительно, пустая граница не ограничивает
+	From, To string
+	// nil - все задачи, true - только повторяющиеся, false - только разовые
+	HasRepeat *bool
+	// метки в виде TagName, которые должны быть у задачи все сразу
+	Tags []string
+	// айди списка задач, 0 - все списки
+	ListId int
+	// nil - все задачи, true - только ждущие выполнения других задач, false - только те, что можно выполнять
+	Blocked *bool
+	// статус задачи, пустой - все статусы
+	Status string
+	// true - только задачи из архива, false - только остальные
+	Archived bool
+}
+
+// страница списка задач
+type TaskPage struct {
+	Tasks []*Task `json:"tasks"`
+	// курсор следующей страницы, пустой - страница последняя
+	NextCursor string `json:"next_cursor"`
+	// количество всех задач, подходящих под условия
+	Total int `json:"total"`
+}
+
+// содержимое курсора: порядок и ключ последней задачи страницы
+type cursor struct {
+	Sort string   `json:"s"`
+	Desc bool     `json:"d,omitempty"`
+	Keys []string `json:"k,omitempty"`
+	Id   int      `json:"i"`
+}
+
+// функция возвращает порядок выборки с учетом порядка по умолчанию
+func (q TaskQuery) order() string {
+	if q.Sort != "" {
+		return q.Sort
+	}
+	if textSearch(q.Search) {
+		return SortRank
+	}
+	return SortDate
+}
+
+// функция возвращает колонки, по которым упорядочиваются задачи; после них всегда идет айди
+func sortColumns(sort string) ([]string, error) {
+	switch sort {
+	case "", SortDate:
+		return []string{"date", "priority_rank", "due_time"}, nil
+	case SortRank:
+		return []string{"rank"}, nil
+	case SortTitle:
+		return []string{"title"}, nil
+	case SortCreated:
+		return []string{"created_at"}, nil
+	case SortId:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("sort must be %s, %s, %s, %s or %s", SortDate, SortTitle, SortId, SortCreated, SortRank)
+}
+
+// функция возвращает значения колонок порядка для задачи в том же порядке, что и sortColumns
+func sortKeys(task *Task, sort string) []string {
+	switch sort {
+	case SortTitle:
+		return []string{task.Title}
+	case SortCreated:
+		return []string{task.Created}
+	case SortId:
+		return nil
+	case SortRank:
+		return []string{task.rank}
+	}
+	return []string{task.Date, priorityRank(task.Priority), task.DueTime}
+}
+
+// функция кодирования курсора после задачи task
+func encodeCursor(q TaskQuery, task *Task) string {
+	data, _ := json.Marshal(cursor{Sort: q.Sort, Desc: q.Desc, Keys: sortKeys(task, q.order()), Id: task.Id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// функция разбора курсора; курсор должен быть выдан для того же порядка
+func decodeCursor(q TaskQuery, columns []string) (*cursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrBadCursor
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc || len(c.Keys) != len(columns) {
+		return nil, ErrBadCursor
+	}
+	return &c, nil
+}
+
+// функция проверки параметров выборки, возвращает колонки порядка и разобранный курсор
+func prepareQuery(q TaskQuery) ([]string, *cursor, error) {
+	if q.Limit < 1 {
+		return nil, nil, fmt.Errorf("limit must be positive")
+	}
+	columns, err := sortColumns(q.order())
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := decodeCursor(q, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	return columns, c, nil
+}
+
+// функция составления запроса списка задач для SQLite и PostgreSQL; fts - искать слова по таблице scheduler_fts (FTS5),
+// иначе регулярными выражениями; возвращает запрос для подсчета всех задач, запрос страницы
+// (limit+1 задач, чтобы понять, есть ли следующая) и их аргументы
+func buildListQuery(q TaskQuery, fts bool) (count string, countArgs []any, list string, listArgs []any, err error) {
+	columns, c, err := prepareQuery(q)
+	if err != nil {
+		return "", nil, "", nil, err
+	}
+
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	// задачи выбираются из подзапроса, в котором у каждой есть ключ релевантности и отмеченные найденные слова
+	source := "SELECT id," + taskColumns + "," + priorityRankSQL + " AS priority_rank" +
+		",date||due_time AS rank,'' AS title_highlight,'' AS snippet FROM scheduler"
+	if q.Search != "" {
+		expr, err := parseSearch(q.Search)
+		if err != nil {
+			return "", nil, "", nil, err
+		}
+		if match := expr.ftsQuery(); fts && match != "" {
+			// bm25 отрицательный и тем меньше, чем лучше совпадение, а слова в заголовке весят больше слов в комментарии;
+			// для сравнения строкой число сдвигается в положительные и дополняется нулями; задачи, которые нашлись
+			// только по меткам, получают нулевую оценку и идут после остальных
+			source = "SELECT s.id,s." + strings.ReplaceAll(taskColumns, ",", ",s.") + "," + priorityRankSQL + " AS priority_rank" +
+				",printf('%020.10f',coalesce(f.score,0)+1000000) AS rank" +
+				",coalesce(f.title_marked,'') AS title_highlight,coalesce(f.comment_marked,'') AS snippet" +
+				" FROM scheduler s LEFT JOIN (SELECT rowid,bm25(scheduler_fts,10.0,1.0) AS score" +
+				",highlight(scheduler_fts,0,char(57344),char(57345)) AS title_marked" +
+				",snippet(scheduler_fts,1,char(57344),char(57345),'…',12) AS comment_marked" +
+				" FROM scheduler_fts WHERE scheduler_fts MATCH " + arg(match) + ") f ON f.rowid=s.id"
+		}
+		where = append(where, expr.textConditions(arg, fts)...)
+		for _, f := range expr.filters {
+			where = append(where, f.condition(arg))
+		}
+	}
+	if q.From != "" {
+		where = append(where, "date>="+arg(q.From))
+	}
+	if q.To != "" {
+		where = append(where, "date<="+arg(q.To))
+	}
+	if q.HasRepeat != nil {
+		if *q.HasRepeat {
+			where = append(where, "repeat<>''")
+		} else {
+			where = append(where, "repeat=''")
+		}
+	}
+	for _, tag := range q.Tags {
+		where = append(where, searchFilter{column: "tag", value: tag}.condition(arg))
+	}
+	if q.ListId != 0 {
+		where = append(where, "list_id="+arg(q.ListId))
+	}
+	// задачи из корзины не видны никогда
+	where = append(where, "deleted_at=''")
+	if q.Archived {
+		where = append(where, "archived_at<>''")
+	} else {
+		where = append(where, "archived_at=''")
+	}
+	if q.Status != "" {
+		where = append(where, "status="+arg(q.Status))
+	}
+	if q.Blocked != nil {
+		if *q.Blocked {
+			where = append(where, "id IN (SELECT a.task_id FROM ("+blockingDeps+") a)")
+		} else {
+			where = append(where, "id NOT IN (SELECT a.task_id FROM ("+blockingDeps+") a)")
+		}
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+	from := " FROM (" + source + ") AS t"
+	count = "SELECT count(*)" + from + cond
+	countArgs = append([]any(nil), args...)
+
+	// страница начинается после задачи из курсора: сравниваем кортежи (колонки порядка, айди)
+	keyColumns := append(append([]string(nil), columns...), "id")
+	if c != nil {
+		params := make([]string, 0, len(keyColumns))
+		for _, key := range c.Keys {
+			params = append(params, arg(key))
+		}
+		params = append(params, arg(c.Id))
+		op := ">"
+		if q.Desc {
+			op = "<"
+		}
+		where = append(where, fmt.Sprintf("(%s) %s (%s)", strings.Join(keyColumns, ","), op, strings.Join(params, ",")))
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+	dir := ""
+	if q.Desc {
+		dir = " DESC"
+	}
+	order := make([]string, 0, len(keyColumns))
+	for _, col := range keyColumns {
+		order = append(order, col+dir)
+	}
+	list = "SELECT id," + taskColumns + ",rank,title_highlight,snippet" + from + cond +
+		" ORDER BY " + strings.Join(order, ",") + " LIMIT " + arg(q.Limit+1)
+	return count, countArgs, list, args, nil
+}
+
+// функция собирает страницу из задач, прочитанных с запасом в одну задачу
+func makePage(q TaskQuery, tasks []*Task, total int) *TaskPage {
+	page := &TaskPage{Tasks: tasks, Total: total}
+	if len(tasks) > q.Limit {
+		page.Tasks = tasks[:q.Limit]
+		page.NextCursor = encodeCursor(q, page.Tasks[len(page.Tasks)-1])
+	}
+	return page
+}
+
+// функция чтения страницы списка задач из SQLite или PostgreSQL
+func queryPage(db *sql.DB, q TaskQuery, fts bool) (*TaskPage, error) {
+	count, countArgs, list, listArgs, err := buildListQuery(q, fts)
+	if err != nil {
+		return nil, err
+	}
+	var total int
+	if err := db.QueryRow(count, countArgs...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("can't count tasks: %w", err)
+	}
+	rows, err := db.Query(list, listArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("error while SELECT query: %w", err)
+	}
+	defer rows.Close()
+	tasks := make([]*Task, 0, q.Limit+1)
+	for rows.Next() {
+		task := Task{}
+		fields := append([]any{&task.Id}, taskFields(&task)...)
+		if err := rows.Scan(append(fields, &task.rank, &task.TitleHighlight, &task.Snippet)...); err != nil {
+			return nil, fmt.Errorf("error while scan table: %w", err)
+		}
+		tasks = append(tasks, &task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	if err := loadDetails(db, tasks); err != nil {
+		return nil, err
+	}
+	if textSearch(q.Search) {
+		// условие поиска уже проверено при составлении запроса, поэтому ошибки быть не может
+		expr, _ := parseSearch(q.Search)
+		for _, task := range tasks {
+			if fts {
+				task.TitleHighlight = markup(task.TitleHighlight)
+				// snippet возвращает начало комментария, даже если слова нашлись только в заголовке
+				if strings.Contains(task.Snippet, markOpen) {
+					task.Snippet = markup(task.Snippet)
+				} else {
+					task.Snippet = ""
+				}
+			} else {
+				expr.highlight(task)
+			}
+		}
+	}
+	return makePage(q, tasks, total), nil
+}
@@ -0,0 +1,538 @@
+// пакет для работы с БД
+package db
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ошибка в строке поиска
+var ErrBadSearch = errors.New("wrong search")
+
+// ошибка в строке поиска с указанием неверного условия
+type SearchError struct {
+	Search string // строка поиска целиком
+	Clause string // ошибочное условие
+	Pos    int    // позиция условия в строке поиска в символах, считая с единицы
+	Msg    string // описание ошибки
+}
+
+func (e *SearchError) Error() string {
+	return fmt.Sprintf("search %q: %s: %q at position %d", e.Search, e.Msg, e.Clause, e.Pos)
+}
+
+func (e *SearchError) Unwrap() error {
+	return ErrBadSearch
+}
+
+// метки начала и конца найденного фрагмента в тексте из БД, в ответе заменяются на <mark> и </mark>
+const (
+	markOpen  = "\uE000"
+	markClose = "\uE001"
+)
+
+// функция проверяет, что в строке поиска есть слова, по которым задачи можно упорядочить по релевантности
+func textSearch(str string) bool {
+	if str == "" {
+		return false
+	}
+	expr, err := parseSearch(str)
+	return err == nil && expr.positive()
+}
+
+// слово или фраза строки поиска
+type searchTerm struct {
+	text   string
+	phrase bool   // фраза в кавычках ищется целиком, слово - как начало слова
+	column string // title или comment для условий с полем, пустая - в заголовке или в комментарии
+}
+
+// условие строки поиска на колонку задачи: repeat:none, date:>=01.03.2025, tag:work
+type searchFilter struct {
+	column string // repeat, date или tag
+	op     string // для даты: =, <, <=, > или >=
+	value  string // для даты - дата 20060102, для repeat - none, any, rrule или вид короткого правила, для tag - имя метки
+	not    bool
+}
+
+// разобранная строка поиска: задача подходит, если в ней есть все слова хотя бы одной группы any,
+// все слова all, нет ни одного слова из none и выполнены все условия filters
+type searchExpr struct {
+	any     [][]searchTerm
+	all     []searchTerm
+	none    []searchTerm
+	filters []searchFilter
+}
+
+// условие строки поиска с его позицией в символах, считая с единицы
+type searchClause struct {
+	text string
+	pos  int
+}
+
+// функция делит строку поиска на условия по пробелам; пробелы внутри кавычек условие не делят
+func splitClauses(str string) ([]searchClause, error) {
+	var clauses []searchClause
+	start, quote := -1, -1
+	for i, r := range str {
+		switch {
+		case r == '"':
+			if start < 0 {
+				start = i
+			}
+			if quote < 0 {
+				quote = i
+			} else {
+				quote = -1
+			}
+		case unicode.IsSpace(r) && quote < 0:
+			if start >= 0 {
+				clauses = append(clauses, searchClause{str[start:i], utf8.RuneCountInString(str[:start]) + 1})
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	if quote >= 0 {
+		return nil, &SearchError{Search: str, Clause: str[quote:], Pos: utf8.RuneCountInString(str[:quote]) + 1,
+			Msg: "unterminated quote"}
+	}
+	if start >= 0 {
+		clauses = append(clauses, searchClause{str[start:], utf8.RuneCountInString(str[:start]) + 1})
+	}
+	return clauses, nil
+}
+
+// функция разбирает слово или "фразу в кавычках"
+func parseTerm(text string) searchTerm {
+	if len(text) >= 2 && strings.HasPrefix(text, `"`) && strings.HasSuffix(text, `"`) {
+		return searchTerm{text: text[1 : len(text)-1], phrase: true}
+	}
+	return searchTerm{text: text}
+}
+
+// поля, по которым можно задать условие; слово с двоеточием после другого имени (Note:, http://...) ищется как обычное слово
+var searchFields = map[string]bool{"title": true, "comment": true, "repeat": true, "date": true, "tag": true}
+
+// виды коротких правил для условия repeat:
+var repeatKinds = map[string]bool{"d": true, "bd": true, "w": true, "m": true, "mw": true, "mb": true, "y": true}
+
+// функция разбора условия с полем; возвращает слово или условие на колонку либо описание ошибки
+func parseField(field, value string, not bool) (*searchTerm, *searchFilter, string) {
+	switch field {
+	case "title", "comment":
+		term := parseTerm(value)
+		if len(splitWords(term.text)) == 0 {
+			return nil, nil, "field must have a word to find"
+		}
+		term.column = field
+		return &term, nil, ""
+	case "repeat":
+		value = strings.ToLower(value)
+		if value != "none" && value != "any" && value != "rrule" && !repeatKinds[value] {
+			return nil, nil, "repeat must be none, any, rrule or a rule kind (d, bd, w, m, mw, mb, y)"
+		}
+		return nil, &searchFilter{column: "repeat", value: value, not: not}, ""
+	case "date":
+		filter := searchFilter{column: "date", op: "=", not: not}
+		for _, op := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(value, op) {
+				filter.op, value = op, value[len(op):]
+				break
+			}
+		}
+		date, ok := searchDate(value)
+		if !ok {
+			return nil, nil, "date must look like 02.01.2006 with optional <, <=, >, >= before it"
+		}
+		filter.value = date
+		return nil, &filter, ""
+	case "tag":
+		name, err := TagName(value)
+		if err != nil {
+			return nil, nil, err.Error()
+		}
+		return nil, &searchFilter{column: "tag", value: name, not: not}, ""
+	}
+	return nil, nil, ""
+}
+
+// функция разбора строки поиска. Слова ищутся как начала слов в заголовке и комментарии, "фраза в кавычках" - целиком,
+// -слово или NOT слово исключает задачи с этим словом, OR между словами - любое из них, AND или пробел - все сразу.
+// Условия с полем (title:слово, comment:"фраза", repeat:none, date:>=01.03.2025, tag:work или #work,
+// а также дата 02.01.2006 без поля) должны выполняться все, минус или NOT перед условием его отрицает
+func parseSearch(str string) (*searchExpr, error) {
+	clauses, err := splitClauses(str)
+	if err != nil {
+		return nil, err
+	}
+	expr := &searchExpr{}
+	var not, or bool
+	for _, c := range clauses {
+		text := c.text
+		switch text {
+		case "OR":
+			or = true
+			continue
+		case "AND":
+			continue
+		case "NOT":
+			not = true
+			continue
+		}
+		if len(text) > 1 && strings.HasPrefix(text, "-") {
+			not = true
+			text = text[1:]
+		}
+
+		field, value, isField := strings.Cut(text, ":")
+		// #метка - то же, что tag:метка
+		if len(text) > 1 && strings.HasPrefix(text, "#") {
+			field, value, isField = "tag", text[1:], true
+		}
+		date, isDate := searchDate(text)
+		term := parseTerm(text)
+		switch {
+		case isField && searchFields[strings.ToLower(field)]:
+			t, filter, msg := parseField(strings.ToLower(field), value, not)
+			switch {
+			case msg != "":
+				return nil, &SearchError{Search: str, Clause: c.text, Pos: c.pos, Msg: msg}
+			case filter != nil:
+				expr.filters = append(expr.filters, *filter)
+			case not:
+				expr.none = append(expr.none, *t)
+			default:
+				expr.all = append(expr.all, *t)
+			}
+		case isDate:
+			expr.filters = append(expr.filters, searchFilter{column: "date", op: "=", value: date, not: not})
+		case len(splitWords(term.text)) == 0:
+			// слово без букв и цифр ничего не найдет
+		case not:
+			expr.none = append(expr.none, term)
+		case or || len(expr.any) == 0:
+			expr.any = append(expr.any, []searchTerm{term})
+		default:
+			expr.any[len(expr.any)-1] = append(expr.any[len(expr.any)-1], term)
+		}
+		not, or = false, false
+	}
+	if !expr.positive() && len(expr.filters) == 0 {
+		return nil, &SearchError{Search: str, Clause: str, Pos: 1, Msg: "search must have a word or a condition to find"}
+	}
+	return expr, nil
+}
+
+// функция проверяет, что в строке поиска есть слова, которые должны найтись
+func (expr *searchExpr) positive() bool {
+	return len(expr.any) > 0 || len(expr.all) > 0
+}
+
+// функция записывает слово или фразу для запроса FTS5
+func (term searchTerm) fts() string {
+	text := `"` + strings.ReplaceAll(term.text, `"`, `""`) + `"`
+	if !term.phrase {
+		text += "*"
+	}
+	if term.column != "" {
+		text = term.column + " : " + text
+	}
+	return text
+}
+
+// функция составления запроса FTS5 для оценки релевантности и отметки найденных слов: любое из слов, которые
+// должны найтись; пустая строка - таких слов нет
+func (expr *searchExpr) ftsQuery() string {
+	var terms []string
+	for _, group := range expr.any {
+		for _, term := range group {
+			terms = append(terms, term.fts())
+		}
+	}
+	for _, term := range expr.all {
+		terms = append(terms, term.fts())
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// функция составления условия SQL на метку, имя которой начинается со слова или совпадает с фразой
+func (term searchTerm) tagCondition(arg func(any) string) string {
+	name := strings.ToLower(term.text)
+	cond := "g.name=" + arg(name)
+	if !term.phrase {
+		name = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(name)
+		cond = "g.name LIKE " + arg(name+"%") + ` ESCAPE '\'`
+	}
+	return "id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id=tt.tag_id WHERE " + cond + ")"
+}
+
+// функция проверяет, что у задачи есть метка, имя которой начинается со слова или совпадает с фразой;
+// для хранилища в памяти
+func (term searchTerm) matchTag(tags []string) bool {
+	name := strings.ToLower(term.text)
+	return slices.ContainsFunc(tags, func(tag string) bool {
+		return tag == name || !term.phrase && strings.HasPrefix(tag, name)
+	})
+}
+
+// функция составления условий SQL на слова строки поиска: слово без поля ищется в заголовке, комментарии
+// и именах меток задачи; fts - искать в заголовке и комментарии по таблице scheduler_fts (FTS5),
+// иначе регулярными выражениями PostgreSQL; arg добавляет аргумент запроса
+func (expr *searchExpr) textConditions(arg func(any) string, fts bool) []string {
+	cond := func(term searchTerm) string {
+		var text string
+		if fts {
+			text = "id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH " + arg(term.fts()) + ")"
+		} else {
+			words := splitWords(term.text)
+			parts := make([]string, 0, len(words))
+			for _, word := range words {
+				parts = append(parts, word.lower)
+			}
+			// \m - начало слова, \M - конец слова
+			re := `\m` + strings.Join(parts, `\W+`)
+			if term.phrase {
+				re += `\M`
+			}
+			p := arg(re)
+			if term.column != "" {
+				text = fmt.Sprintf("%s ~* %s", term.column, p)
+			} else {
+				text = fmt.Sprintf("title ~* %s OR comment ~* %s", p, p)
+			}
+		}
+		if term.column != "" {
+			return text
+		}
+		return "(" + text + " OR " + term.tagCondition(arg) + ")"
+	}
+	var conds []string
+	if len(expr.any) > 0 {
+		groups := make([]string, 0, len(expr.any))
+		for _, group := range expr.any {
+			terms := make([]string, 0, len(group))
+			for _, term := range group {
+				terms = append(terms, cond(term))
+			}
+			groups = append(groups, "("+strings.Join(terms, " AND ")+")")
+		}
+		conds = append(conds, "("+strings.Join(groups, " OR ")+")")
+	}
+	for _, term := range expr.all {
+		conds = append(conds, cond(term))
+	}
+	for _, term := range expr.none {
+		conds = append(conds, "NOT "+cond(term))
+	}
+	return conds
+}
+
+// функция составления условия SQL на колонку задачи
+func (f searchFilter) condition(arg func(any) string) string {
+	var cond string
+	switch {
+	case f.column == "date":
+		cond = "date" + f.op + arg(f.value)
+	case f.column == "tag":
+		cond = "id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id=tt.tag_id WHERE g.name=" + arg(f.value) + ")"
+	case f.value == "none":
+		cond = "repeat=''"
+	case f.value == "any":
+		cond = "repeat<>''"
+	case f.value == "rrule":
+		cond = "upper(repeat) LIKE '%FREQ=%'"
+	default:
+		cond = fmt.Sprintf("(repeat=%s OR repeat LIKE %s)", arg(f.value), arg(f.value+" %"))
+	}
+	if f.not {
+		return "NOT " + cond
+	}
+	return cond
+}
+
+// функция проверяет условие на колонку задачи; для хранилища в памяти
+func (f searchFilter) match(task *Task) bool {
+	var ok bool
+	switch {
+	case f.column == "date":
+		switch f.op {
+		case "=":
+			ok = task.Date == f.value
+		case "<":
+			ok = task.Date < f.value
+		case "<=":
+			ok = task.Date <= f.value
+		case ">":
+			ok = task.Date > f.value
+		case ">=":
+			ok = task.Date >= f.value
+		}
+	case f.column == "tag":
+		ok = slices.Contains(task.Tags, f.value)
+	case f.value == "none":
+		ok = task.Repeat == ""
+	case f.value == "any":
+		ok = task.Repeat != ""
+	case f.value == "rrule":
+		ok = strings.Contains(strings.ToUpper(task.Repeat), "FREQ=")
+	default:
+		ok = task.Repeat == f.value || strings.HasPrefix(task.Repeat, f.value+" ")
+	}
+	return ok != f.not
+}
+
+// слово текста: границы в байтах и само слово в нижнем регистре
+type textWord struct {
+	start, end int
+	lower      string
+}
+
+// функция делит текст на слова из букв и цифр так же, как токенизатор unicode61
+func splitWords(text string) []textWord {
+	var words []textWord
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			words = append(words, textWord{start, i, strings.ToLower(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, textWord{start, len(text), strings.ToLower(text[start:])})
+	}
+	return words
+}
+
+// функция возвращает номера слов текста, с которых начинается слово или фраза
+func (term searchTerm) find(words []textWord) []int {
+	want := splitWords(term.text)
+	var found []int
+	for i := 0; i+len(want) <= len(words); i++ {
+		match := true
+		for j, w := range want {
+			// последнее слово без кавычек - начало слова
+			prefix := !term.phrase && j == len(want)-1 && strings.HasPrefix(words[i+j].lower, w.lower)
+			if words[i+j].lower != w.lower && !prefix {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// функция проверяет задачу по строке поиска; для хранилища в памяти
+func (expr *searchExpr) match(task *Task) bool {
+	title, comment := splitWords(task.Title), splitWords(task.Comment)
+	has := func(term searchTerm) bool {
+		return (term.column != "comment" && len(term.find(title)) > 0) ||
+			(term.column != "title" && len(term.find(comment)) > 0) ||
+			(term.column == "" && term.matchTag(task.Tags))
+	}
+	for _, f := range expr.filters {
+		if !f.match(task) {
+			return false
+		}
+	}
+	for _, term := range expr.none {
+		if has(term) {
+			return false
+		}
+	}
+	for _, term := range expr.all {
+		if !has(term) {
+			return false
+		}
+	}
+	if len(expr.any) == 0 {
+		return true
+	}
+	for _, group := range expr.any {
+		all := true
+		for _, term := range group {
+			all = all && has(term)
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// функция отмечает в тексте колонки column все слова, найденные строкой поиска
+func (expr *searchExpr) mark(text, column string) string {
+	words := splitWords(text)
+	marked := make([]bool, len(words))
+	terms := append([]searchTerm(nil), expr.all...)
+	for _, group := range expr.any {
+		terms = append(terms, group...)
+	}
+	for _, term := range terms {
+		if term.column != "" && term.column != column {
+			continue
+		}
+		n := len(splitWords(term.text))
+		for _, i := range term.find(words) {
+			for j := i; j < i+n; j++ {
+				marked[j] = true
+			}
+		}
+	}
+	var b strings.Builder
+	last := 0
+	for i, word := range words {
+		if marked[i] {
+			b.WriteString(text[last:word.start] + markOpen + text[word.start:word.end] + markClose)
+			last = word.end
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// функция заменяет метки найденных фрагментов на <mark> и </mark>, остальной текст экранируется для HTML
+func markup(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, markOpen, "<mark>")
+	return strings.ReplaceAll(text, markClose, "</mark>")
+}
+
+// функция вырезает из отмеченного текста фрагмент вокруг первого найденного слова, примерно по words слов с каждой стороны;
+// если в тексте ничего не отмечено, фрагмент пустой
+func snippetAround(text string, words int) string {
+	i := strings.Index(text, markOpen)
+	if i < 0 {
+		return ""
+	}
+	before := strings.Fields(text[:i])
+	after := strings.Fields(text[i:])
+	prefix, suffix := "", ""
+	if len(before) > words {
+		before, prefix = before[len(before)-words:], "…"
+	}
+	if len(after) > words+1 {
+		after, suffix = after[:words+1], "…"
+	}
+	return prefix + strings.Join(append(before, after...), " ") + suffix
+}
+
+// функция заполняет отмеченные заголовок и фрагмент комментария задачи для хранилищ без FTS5
+func (expr *searchExpr) highlight(task *Task) {
+	task.TitleHighlight = markup(expr.mark(task.Title, "title"))
+	task.Snippet = markup(snippetAround(expr.mark(task.Comment, "comment"), 6))
+}
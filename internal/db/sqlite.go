@@ -0,0 +1,393 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	_ "modernc.org/sqlite"
+)
+
+// хранилище задач в файле SQLite
+type SQLiteStore struct {
+	db *sql.DB
+	*migrator
+}
+
+// функция открытия хранилища в файле SQLite без изменения схемы; если файла нет, он будет создан
+func OpenSQLite(dbFileName string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", dbFileName)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't open db-file: %w", err)
+	}
+	s := &SQLiteStore{db: db}
+	s.migrator = &migrator{
+		db:         db,
+		dir:        "migrations/sqlite",
+		tableQuery: "SELECT count(*) FROM sqlite_master WHERE type='table' AND name=$1",
+		legacy:     s.legacyVersion,
+	}
+	return s, nil
+}
+
+// функция закрытия хранилища
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// функция определяет версию схемы базы, созданной до появления миграций, по ее таблицам и колонкам
+func (s *SQLiteStore) legacyVersion() (int, error) {
+	version := 0
+	for _, check := range []func() (bool, error){
+		func() (bool, error) { return s.tableExists("scheduler") },
+		func() (bool, error) { return s.columnExists("scheduler", "remaining") },
+		func() (bool, error) { return s.tableExists("exceptions") },
+		func() (bool, error) { return s.columnExists("scheduler", "repeat_mode") },
+		func() (bool, error) { return s.columnExists("scheduler", "due_time") },
+	} {
+		ok, err := check()
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		version++
+	}
+	return version, nil
+}
+
+// функция проверки наличия колонки в таблице
+func (s *SQLiteStore) columnExists(table, column string) (bool, error) {
+	var n int
+	err := s.db.QueryRow("SELECT count(*) FROM pragma_table_info(:table) WHERE name=:column",
+		sql.Named("table", table), sql.Named("column", column)).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("can't read columns of %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+// функция добавления новой записи в БД
+func (s *SQLiteStore) AddTask(task *Task) (int64, error) {
+	var id int64
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec("INSERT INTO scheduler ("+taskColumns+") VALUES (:date,:title,:comment,:repeat,:remaining,:repeat_mode,:due_time,:timezone,:created_at,:list_id,:priority,:status,:estimate,:archived_at,:deleted_at)",
+			sql.Named("date", task.Date),
+			sql.Named("title", task.Title),
+			sql.Named("comment", task.Comment),
+			sql.Named("repeat", task.Repeat),
+			sql.Named("remaining", task.Remaining),
+			sql.Named("repeat_mode", task.RepeatMode),
+			sql.Named("due_time", task.DueTime),
+			sql.Named("timezone", task.TimeZone),
+			sql.Named("created_at", task.Created),
+			sql.Named("list_id", listOrDefault(task.ListId)),
+			sql.Named("priority", priorityOrDefault(task.Priority)),
+			sql.Named("status", statusOrDefault(task.Status)),
+			sql.Named("estimate", task.Estimate),
+			sql.Named("archived_at", task.Archived),
+			sql.Named("deleted_at", task.Deleted))
+		if err != nil {
+			return fmt.Errorf("can't insert new task: %w", err)
+		}
+		id, err = res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("can't get index of inserted task: %w", err)
+		}
+		// у новой задачи прежних меток нет
+		if len(task.Tags) == 0 {
+			return nil
+		}
+		return saveTags(tx, int(id), task.Tags)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// функция чтения страницы списка задач с порядком, фильтрами и курсором
+func (s *SQLiteStore) ListTasks(q TaskQuery) (*TaskPage, error) {
+	return queryPage(s.db, q, true)
+}
+
+// функция запроса записи БД по айди
+func (s *SQLiteStore) GetTask(id string) (*Task, error) {
+	var task Task
+	var err error
+	task.Id, err = strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	row := s.db.QueryRow("SELECT "+taskColumns+" FROM scheduler WHERE id=:id AND archived_at='' AND deleted_at=''", sql.Named("id", id))
+	if err := row.Scan(taskFields(&task)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("can't get task: %w", err)
+	}
+	if err := loadDetails(s.db, []*Task{&task}); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// функция изменения всех полей записи БД по айди
+func (s *SQLiteStore) UpdTask(task *Task) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		// запросили
+		res, err := tx.Exec("UPDATE scheduler SET date=:date,title=:title,comment=:comment,repeat=:repeat,remaining=:remaining,repeat_mode=:repeat_mode,due_time=:due_time,timezone=:timezone,list_id=:list_id,priority=:priority,status=:status,estimate=:estimate WHERE id=:id AND archived_at='' AND deleted_at=''",
+			sql.Named("date", task.Date),
+			sql.Named("title", task.Title),
+			sql.Named("comment", task.Comment),
+			sql.Named("repeat", task.Repeat),
+			sql.Named("remaining", task.Remaining),
+			sql.Named("repeat_mode", task.RepeatMode),
+			sql.Named("due_time", task.DueTime),
+			sql.Named("timezone", task.TimeZone),
+			sql.Named("list_id", listOrDefault(task.ListId)),
+			sql.Named("priority", priorityOrDefault(task.Priority)),
+			sql.Named("status", statusOrDefault(task.Status)),
+			sql.Named("estimate", task.Estimate),
+			sql.Named("id", task.Id))
+		if err != nil {
+			return fmt.Errorf("can't update task: %w", err)
+		}
+		// проверили количество измененных
+		num, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("can't check updated rows: %w", err)
+		}
+		// если их нет, то ошибка
+		if num == 0 {
+			return ErrNotFound
+		}
+		return saveTags(tx, task.Id, task.Tags)
+	})
+}
+
+// функция окончательного удаления записи, ее исключений, меток, чек-листа, зависимостей и выполнений по айди
+func (s *SQLiteStore) DelTask(id string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	// удаляем все в одной транзакции, чтобы при ошибке не остались записи без задачи
+	return inTx(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM scheduler WHERE id=:id", sql.Named("id", taskId))
+		if err != nil {
+			return fmt.Errorf("can't delete task: %w", err)
+		}
+		//проверяем, что удалилась
+		if err := checkAffected(res); err != nil {
+			return err
+		}
+		// исключения удаленной задачи больше не нужны
+		if _, err := tx.Exec("DELETE FROM exceptions WHERE task_id=:id", sql.Named("id", taskId)); err != nil {
+			return fmt.Errorf("can't delete task exceptions: %w", err)
+		}
+		// и метки тоже
+		if err := delTaskTags(tx, taskId); err != nil {
+			return err
+		}
+		// и чек-лист
+		if err := delTaskChecklist(tx, taskId); err != nil {
+			return err
+		}
+		// а задачи, которые ее ждали, больше не ждут
+		if err := delTaskDeps(tx, taskId); err != nil {
+			return err
+		}
+		// история выполнений удаленной задачи тоже не нужна
+		return delTaskCompletions(tx, taskId)
+	})
+}
+
+// функция изменения поля с датой записи и количества оставшихся повторений
+func (s *SQLiteStore) UpDateTask(next string, remaining int, id string) error {
+	res, err := s.db.Exec("UPDATE scheduler SET date=:date,remaining=:remaining WHERE id=:id AND archived_at='' AND deleted_at=''",
+		sql.Named("date", next),
+		sql.Named("remaining", remaining),
+		sql.Named("id", id))
+	if err != nil {
+		return fmt.Errorf("can't update task date: %w", err)
+	}
+	// проверяем, запись нашлась
+	num, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can't check updated task date: %w", err)
+	}
+
+	if num == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// функция добавления исключения; повторная запись для той же даты серии заменяет прежнюю
+func (s *SQLiteStore) AddException(ex *Exception) error {
+	return addException(s.db, ex)
+}
+
+// функция удаления исключения для даты серии
+func (s *SQLiteStore) DelException(taskId int, date string) error {
+	return delException(s.db, taskId, date)
+}
+
+// функция чтения всех исключений задачи
+func (s *SQLiteStore) Exceptions(taskId int) ([]*Exception, error) {
+	return queryExceptions(s.db, taskId)
+}
+
+// функция чтения всех меток с количеством задач
+func (s *SQLiteStore) Tags() ([]*Tag, error) {
+	return queryTags(s.db)
+}
+
+// функция переименования метки у всех задач
+func (s *SQLiteStore) RenameTag(name, newName string) (*Tag, error) {
+	return renameTag(s.db, name, newName)
+}
+
+// функция переноса задач с одной метки на другую
+func (s *SQLiteStore) MergeTag(name, into string) (*Tag, error) {
+	return mergeTag(s.db, name, into)
+}
+
+// функция удаления метки у всех задач
+func (s *SQLiteStore) DelTag(name string) error {
+	return delTag(s.db, name)
+}
+
+// функция чтения всех списков с количеством задач
+func (s *SQLiteStore) Lists() ([]*List, error) {
+	return queryLists(s.db, "")
+}
+
+// функция чтения списка по айди
+func (s *SQLiteStore) GetList(id int) (*List, error) {
+	return getList(s.db, id)
+}
+
+// функция добавления списка
+func (s *SQLiteStore) AddList(list *List) (int64, error) {
+	return addList(s.db, list)
+}
+
+// функция изменения списка
+func (s *SQLiteStore) UpdList(list *List) error {
+	return updList(s.db, list)
+}
+
+// функция удаления списка с переносом его задач в другой список или в корзину
+func (s *SQLiteStore) DelList(id, moveTo int, at string) error {
+	return delList(s.db, id, moveTo, at)
+}
+
+// функция переноса задачи в другой список
+func (s *SQLiteStore) MoveTask(id string, listId int) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	return moveTask(s.db, taskId, listId)
+}
+
+// функция изменения статуса задачи
+func (s *SQLiteStore) SetStatus(id string, status string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	return setStatus(s.db, taskId, status)
+}
+
+// функция чтения пунктов чек-листа задачи
+func (s *SQLiteStore) ChecklistItems(taskId int) ([]*ChecklistItem, error) {
+	return queryChecklist(s.db, taskId)
+}
+
+// функция добавления пункта чек-листа
+func (s *SQLiteStore) AddChecklistItem(item *ChecklistItem) (int64, error) {
+	return addChecklistItem(s.db, item)
+}
+
+// функция изменения пункта чек-листа
+func (s *SQLiteStore) UpdChecklistItem(item *ChecklistItem) error {
+	return updChecklistItem(s.db, item)
+}
+
+// функция удаления пункта чек-листа вместе с вложенными
+func (s *SQLiteStore) DelChecklistItem(taskId, id int) error {
+	return delChecklistItem(s.db, taskId, id)
+}
+
+// функция добавления зависимости задачи
+func (s *SQLiteStore) AddDependency(taskId, dependsOn int) error {
+	return addDependency(s.db, taskId, dependsOn)
+}
+
+// функция удаления зависимости задачи
+func (s *SQLiteStore) DelDependency(taskId, dependsOn int) error {
+	return delDependency(s.db, taskId, dependsOn)
+}
+
+// функция чтения цепочки зависимостей задачи
+func (s *SQLiteStore) TaskGraph(taskId int) (*Graph, error) {
+	return taskGraph(s.db, taskId)
+}
+
+// функция перехода задачи на следующее повторение или в архив вместе с записью выполнения
+func (s *SQLiteStore) AdvanceTask(a *Advance) error {
+	return advanceTask(s.db, a)
+}
+
+// функция чтения выполнений с датами повторений от from до to включительно
+func (s *SQLiteStore) Completions(from, to string, limit int) ([]*Completion, error) {
+	return queryCompletions(s.db, from, to, limit)
+}
+
+// функция чтения последнего выполнения
+func (s *SQLiteStore) LastCompletion() (*Completion, error) {
+	return lastCompletion(s.db)
+}
+
+// функция отмены выполнения
+func (s *SQLiteStore) UndoCompletion(c *Completion) error {
+	return undoCompletion(s.db, c)
+}
+
+// функция переноса задачи в корзину
+func (s *SQLiteStore) TrashTask(id string, at string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	return trashTask(s.db, taskId, at)
+}
+
+// функция чтения задач из корзины, сначала последние удаленные
+func (s *SQLiteStore) Trash(limit int) ([]*Task, error) {
+	return trashTasks(s.db, limit)
+}
+
+// функция возврата задачи из корзины
+func (s *SQLiteStore) RestoreTask(id string) error {
+	taskId, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("can't convert ID to int: %w", err)
+	}
+	return restoreTask(s.db, taskId)
+}
+
+// функция окончательного удаления задач, попавших в корзину раньше before
+func (s *SQLiteStore) PurgeTrash(before string) (int, error) {
+	return purgeTrash(s.db, before)
+}
@@ -0,0 +1,47 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// выражение SQL с местом приоритета задачи в порядке Priorities: срочные задачи идут первыми;
+// место строкой, чтобы сравнивать его с ключом курсора так же, как в sortKeys
+const priorityRankSQL = "CASE priority WHEN 'urgent' THEN '0' WHEN 'high' THEN '1' WHEN 'low' THEN '3' ELSE '2' END"
+
+// функция возвращает место приоритета в порядке Priorities строкой, как priorityRankSQL
+func priorityRank(priority string) string {
+	rank := slices.Index(Priorities, priority)
+	if rank < 0 {
+		rank = slices.Index(Priorities, PriorityNormal)
+	}
+	return strconv.Itoa(rank)
+}
+
+// функция возвращает приоритет задачи, пустой заменяется обычным
+func priorityOrDefault(priority string) string {
+	if priority == "" {
+		return PriorityNormal
+	}
+	return priority
+}
+
+// функция возвращает статус задачи, пустой заменяется статусом новой задачи
+func statusOrDefault(status string) string {
+	if status == "" {
+		return StatusTodo
+	}
+	return status
+}
+
+// функция изменения статуса задачи в SQLite или PostgreSQL
+func setStatus(db *sql.DB, taskId int, status string) error {
+	res, err := db.Exec("UPDATE scheduler SET status=$1 WHERE id=$2 AND archived_at='' AND deleted_at=''", statusOrDefault(status), taskId)
+	if err != nil {
+		return fmt.Errorf("can't set task status: %w", err)
+	}
+	return checkAffected(res)
+}
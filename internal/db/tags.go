@@ -0,0 +1,275 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// метка с количеством задач, у которых она есть
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ошибки для операций с метками
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+// максимальная длина имени метки в символах
+const maxTagLen = 64
+
+// функция приводит имя метки к виду, в котором она хранится: без # в начале и в нижнем регистре
+func TagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return "", errors.New("tag must not be empty")
+	}
+	if utf8.RuneCountInString(name) > maxTagLen {
+		return "", fmt.Errorf("tag must be at most %d characters", maxTagLen)
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == '#' }) {
+		return "", fmt.Errorf("tag %q must not contain spaces, commas or #", name)
+	}
+	return name, nil
+}
+
+// функция проверки меток задачи: имена приводятся к виду TagName, повторы убираются, метки упорядочиваются
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	ret := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name, err := TagName(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// запросы без результата в транзакции и без нее
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// функция записи меток задачи в SQLite или PostgreSQL вместо прежних
+func saveTags(tx execer, taskId int, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM task_tags WHERE task_id=$1", taskId); err != nil {
+		return fmt.Errorf("can't delete task tags: %w", err)
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", tag); err != nil {
+			return fmt.Errorf("can't save tag: %w", err)
+		}
+		if _, err := tx.Exec("INSERT INTO task_tags (task_id,tag_id) SELECT $1,id FROM tags WHERE name=$2", taskId, tag); err != nil {
+			return fmt.Errorf("can't save task tag: %w", err)
+		}
+	}
+	return dropUnusedTags(tx)
+}
+
+// функция удаления меток задачи, например вместе с задачей
+func delTaskTags(tx execer, taskId int) error {
+	if _, err := tx.Exec("DELETE FROM task_tags WHERE task_id=$1", taskId); err != nil {
+		return fmt.Errorf("can't delete task tags: %w", err)
+	}
+	return dropUnusedTags(tx)
+}
+
+// функция удаления меток, которых нет ни у одной задачи
+func dropUnusedTags(tx execer) error {
+	if _, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM task_tags)"); err != nil {
+		return fmt.Errorf("can't delete unused tags: %w", err)
+	}
+	return nil
+}
+
+// функция чтения меток задач одним запросом
+func loadTags(db *sql.DB, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byId := make(map[int]*Task, len(tasks))
+	params := make([]string, 0, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, task := range tasks {
+		byId[task.Id] = task
+		args = append(args, task.Id)
+		params = append(params, fmt.Sprintf("$%d", len(args)))
+	}
+	rows, err := db.Query("SELECT tt.task_id,g.name FROM task_tags tt JOIN tags g ON g.id=tt.tag_id WHERE tt.task_id IN ("+
+		strings.Join(params, ",")+") ORDER BY g.name", args...)
+	if err != nil {
+		return fmt.Errorf("error while query for tags: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return fmt.Errorf("error while scan tags: %w", err)
+		}
+		if task := byId[id]; task != nil {
+			task.Tags = append(task.Tags, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("some error in cursor: %w", err)
+	}
+	return nil
+}
+
+// функция чтения всех меток с количеством задач из SQLite или PostgreSQL, сначала самые частые
+func queryTags(db *sql.DB) ([]*Tag, error) {
+	rows, err := db.Query(`SELECT g.name,count(*) FROM tags g JOIN task_tags tt ON tt.tag_id=g.id
+		JOIN scheduler s ON s.id=tt.task_id AND s.archived_at='' AND s.deleted_at='' GROUP BY g.name ORDER BY count(*) DESC,g.name`)
+	if err != nil {
+		return nil, fmt.Errorf("error while query for tags: %w", err)
+	}
+	defer rows.Close()
+	tags := []*Tag{}
+	for rows.Next() {
+		tag := Tag{}
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, fmt.Errorf("error while scan tags: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	return tags, nil
+}
+
+// функция возвращает айди метки по имени
+func tagId(tx *sql.Tx, name string) (int, error) {
+	var id int
+	err := tx.QueryRow("SELECT id FROM tags WHERE name=$1", name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTagNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("can't get tag: %w", err)
+	}
+	return id, nil
+}
+
+// функция выполняет изменения в транзакции SQLite или PostgreSQL
+func inTx(db *sql.DB, f func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// функция возвращает метку с количеством задач не из архива и не из корзины, как в queryTags;
+// у метки, которая есть только у таких задач, количество нулевое
+func countTag(tx *sql.Tx, id int, name string) (*Tag, error) {
+	tag := Tag{Name: name}
+	err := tx.QueryRow(`SELECT count(*) FROM task_tags tt JOIN scheduler s ON s.id=tt.task_id
+		AND s.archived_at='' AND s.deleted_at='' WHERE tt.tag_id=$1`, id).Scan(&tag.Count)
+	if err != nil {
+		return nil, fmt.Errorf("can't count tag tasks: %w", err)
+	}
+	return &tag, nil
+}
+
+// функция переименования метки в SQLite или PostgreSQL; переименование в то же имя ничего не меняет
+func renameTag(db *sql.DB, name, newName string) (*Tag, error) {
+	var tag *Tag
+	err := inTx(db, func(tx *sql.Tx) error {
+		id, err := tagId(tx, name)
+		if err != nil {
+			return err
+		}
+		if name != newName {
+			if _, err := tagId(tx, newName); err == nil {
+				return ErrTagExists
+			} else if !errors.Is(err, ErrTagNotFound) {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE tags SET name=$1 WHERE id=$2", newName, id); err != nil {
+				return fmt.Errorf("can't rename tag: %w", err)
+			}
+		}
+		tag, err = countTag(tx, id, newName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// функция переноса задач с метки name на метку into в SQLite или PostgreSQL
+func mergeTag(db *sql.DB, name, into string) (*Tag, error) {
+	var tag *Tag
+	err := inTx(db, func(tx *sql.Tx) error {
+		from, err := tagId(tx, name)
+		if err != nil {
+			return err
+		}
+		if name == into {
+			tag, err = countTag(tx, from, into)
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", into); err != nil {
+			return fmt.Errorf("can't save tag: %w", err)
+		}
+		to, err := tagId(tx, into)
+		if err != nil {
+			return err
+		}
+		// у задачи могут быть обе метки, тогда вторая запись не нужна
+		if _, err := tx.Exec(`INSERT INTO task_tags (task_id,tag_id) SELECT task_id,$1 FROM task_tags WHERE tag_id=$2
+			ON CONFLICT (task_id,tag_id) DO NOTHING`, to, from); err != nil {
+			return fmt.Errorf("can't merge tags: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM task_tags WHERE tag_id=$1", from); err != nil {
+			return fmt.Errorf("can't merge tags: %w", err)
+		}
+		if err := dropUnusedTags(tx); err != nil {
+			return err
+		}
+		tag, err = countTag(tx, to, into)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// функция удаления метки у всех задач в SQLite или PostgreSQL
+func delTag(db *sql.DB, name string) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		id, err := tagId(tx, name)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM task_tags WHERE tag_id=$1", id); err != nil {
+			return fmt.Errorf("can't delete tag: %w", err)
+		}
+		return dropUnusedTags(tx)
+	})
+}
@@ -1,6 +1,37 @@
 // пакет для работы с БД
 package db
 
+// режимы повторения задачи
+const (
+	// следующая дата считается от даты задачи по расписанию
+	RepeatFixed = "fixed"
+	// следующая дата считается от дня, когда задачу выполнили
+	RepeatAfterCompletion = "after-completion"
+)
+
+// приоритеты задачи от низшего к высшему
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// статусы задачи на доске в порядке колонок
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusWaiting    = "waiting"
+	StatusDone       = "done"
+)
+
+var (
+	// все приоритеты, сначала самый высокий
+	Priorities = []string{PriorityUrgent, PriorityHigh, PriorityNormal, PriorityLow}
+	// все статусы в порядке колонок доски
+	Statuses = []string{StatusTodo, StatusInProgress, StatusWaiting, StatusDone}
+)
+
 // структура записи в планировщике
 type Task struct {
 	Id      int    `json:"id,string"`
@@ -8,4 +39,46 @@ type Task struct {
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	// сколько раз осталось выполнить задачу по правилу с ограничением count, 0 - без ограничения
+	Remaining int `json:"remaining,string,omitempty"`
+	// режим повторения: RepeatFixed или RepeatAfterCompletion
+	RepeatMode string `json:"repeat_mode"`
+	// время дня в формате 15:04, к которому задачу нужно выполнить, пустое - в течение дня
+	DueTime string `json:"time,omitempty"`
+	// часовой пояс задачи из базы IANA (Europe/Moscow), пустой - пояс сервера по умолчанию
+	TimeZone string `json:"timezone,omitempty"`
+	// время создания задачи в формате RFC 3339
+	Created string `json:"created,omitempty"`
+	// айди списка, в котором задача; при добавлении 0 - список по умолчанию
+	ListId int `json:"list_id,string"`
+	// приоритет, один из Priority...; при добавлении пустой - PriorityNormal
+	Priority string `json:"priority"`
+	// статус на доске, один из Status...; при добавлении пустой - StatusTodo
+	Status string `json:"status"`
+	// оценка времени на выполнение в минутах, 0 - без оценки
+	Estimate int `json:"estimate,string,omitempty"`
+	// время переноса в архив в формате RFC 3339, пустое - задача не в архиве
+	Archived string `json:"archived,omitempty"`
+	// время переноса в корзину в формате RFC 3339, пустое - задача не в корзине
+	Deleted string `json:"deleted,omitempty"`
+	// метки задачи в виде TagName, упорядоченные по имени
+	Tags []string `json:"tags,omitempty"`
+	// сколько пунктов чек-листа выполнено, nil - чек-листа нет; вычисляется и в базе задачи не хранится
+	Progress *Progress `json:"progress,omitempty"`
+	// задача ждет выполнения других задач, от которых зависит; вычисляется и в базе задачи не хранится
+	Blocked bool `json:"blocked,omitempty"`
+	// срок выполнения в формате RFC 3339, вычисляется по дате, времени и поясу и в базе не хранится
+	Due string `json:"due,omitempty"`
+	// заголовок и фрагмент комментария с найденными словами в <mark>, заполняются только при поиске по словам
+	TitleHighlight string `json:"title_highlight,omitempty"`
+	Snippet        string `json:"snippet,omitempty"`
+	// ключ порядка по релевантности, в базе не хранится
+	rank string
+}
+
+// исключение из серии повторяющейся задачи: пропущенное (NewDate пустая) или перенесенное повторение
+type Exception struct {
+	TaskId  int    `json:"task_id,string"`
+	Date    string `json:"date"`     // исходная дата повторения по правилу
+	NewDate string `json:"new_date"` // дата, на которую повторение перенесено
 }
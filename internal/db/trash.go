@@ -0,0 +1,82 @@
+// пакет для работы с БД
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// функция переноса задачи в корзину в SQLite или PostgreSQL; at - время удаления
+func trashTask(db *sql.DB, taskId int, at string) error {
+	res, err := db.Exec("UPDATE scheduler SET deleted_at=$1 WHERE id=$2 AND deleted_at=''", at, taskId)
+	if err != nil {
+		return fmt.Errorf("can't trash task: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// функция чтения задач из корзины в SQLite или PostgreSQL, сначала последние удаленные
+func trashTasks(db *sql.DB, limit int) ([]*Task, error) {
+	rows, err := db.Query("SELECT id,"+taskColumns+" FROM scheduler WHERE deleted_at<>'' ORDER BY deleted_at DESC,id DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, fmt.Errorf("error while query for trash: %w", err)
+	}
+	defer rows.Close()
+	tasks := make([]*Task, 0, limit)
+	for rows.Next() {
+		task := Task{}
+		if err := rows.Scan(append([]any{&task.Id}, taskFields(&task)...)...); err != nil {
+			return nil, fmt.Errorf("error while scan trash: %w", err)
+		}
+		tasks = append(tasks, &task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("some error in cursor: %w", err)
+	}
+	if err := loadDetails(db, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// функция возврата задачи из корзины в SQLite или PostgreSQL; ErrNotFound, если в корзине ее нет
+func restoreTask(db *sql.DB, taskId int) error {
+	res, err := db.Exec("UPDATE scheduler SET deleted_at='' WHERE id=$1 AND deleted_at<>''", taskId)
+	if err != nil {
+		return fmt.Errorf("can't restore task: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// функция окончательного удаления задач, попавших в корзину раньше before, вместе с их исключениями, метками,
+// чек-листами, зависимостями и выполнениями из SQLite или PostgreSQL; возвращает количество удаленных задач
+func purgeTrash(db *sql.DB, before string) (int, error) {
+	var purged int64
+	err := inTx(db, func(tx *sql.Tx) error {
+		const trashed = "(SELECT id FROM scheduler WHERE deleted_at<>'' AND deleted_at<$1)"
+		for _, query := range []string{
+			"DELETE FROM exceptions WHERE task_id IN " + trashed,
+			"DELETE FROM task_tags WHERE task_id IN " + trashed,
+			"DELETE FROM checklist_items WHERE task_id IN " + trashed,
+			"DELETE FROM completions WHERE task_id IN " + trashed,
+			"DELETE FROM task_dependencies WHERE task_id IN " + trashed + " OR depends_on IN " + trashed,
+		} {
+			if _, err := tx.Exec(query, before); err != nil {
+				return fmt.Errorf("can't purge trashed tasks: %w", err)
+			}
+		}
+		res, err := tx.Exec("DELETE FROM scheduler WHERE deleted_at<>'' AND deleted_at<$1", before)
+		if err != nil {
+			return fmt.Errorf("can't purge trashed tasks: %w", err)
+		}
+		purged, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("can't check purged rows: %w", err)
+		}
+		return dropUnusedTags(tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(purged), nil
+}
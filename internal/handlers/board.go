@@ -0,0 +1,154 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// ошибки в приоритете и статусе задачи
+var (
+	errPriority = fmt.Errorf("priority must be %s, %s, %s or %s",
+		db.PriorityLow, db.PriorityNormal, db.PriorityHigh, db.PriorityUrgent)
+	errStatus = fmt.Errorf("status must be %s, %s, %s or %s",
+		db.StatusTodo, db.StatusInProgress, db.StatusWaiting, db.StatusDone)
+)
+
+// структура для приема статуса задачи в джисоне
+type jsonStatus struct {
+	Status string `json:"status"`
+}
+
+// колонка доски: статус и страница задач с ним
+type boardColumn struct {
+	Status string `json:"status"`
+	*db.TaskPage
+}
+
+// структура с колонками доски для вывода в джисоне
+type boardResp struct {
+	Columns []*boardColumn `json:"columns"`
+}
+
+// функция проверки приоритета, статуса и оценки задачи; пустые приоритет и статус заменяются обычными
+func checkWorkflow(task *db.Task) error {
+	if task.Priority == "" {
+		task.Priority = db.PriorityNormal
+	}
+	if !slices.Contains(db.Priorities, task.Priority) {
+		return &validationError{field: "priority", err: errPriority}
+	}
+	if task.Status == "" {
+		task.Status = db.StatusTodo
+	}
+	if !slices.Contains(db.Statuses, task.Status) {
+		return &validationError{field: "status", err: errStatus}
+	}
+	if task.Estimate < 0 {
+		return &validationError{field: "estimate", err: errors.New("estimate must not be negative")}
+	}
+	return nil
+}
+
+// функция разбора статуса из параметра или поля field
+func parseStatus(str, field string) (string, error) {
+	if !slices.Contains(db.Statuses, str) {
+		return "", &validationError{field: field, err: errStatus}
+	}
+	return str, nil
+}
+
+// функция переноса задачи в колонку доски со статусом из параметра или поля field
+func (h *Handlers) setStatus(id string, statusStr, field string) error {
+	status, err := parseStatus(statusStr, field)
+	if err != nil {
+		return err
+	}
+	return h.store.SetStatus(id, status)
+}
+
+// функция чтения доски: колонка на каждый статус, а если статус задан в запросе - только его колонка;
+// курсор листает одну колонку, поэтому без статуса не принимается
+func (h *Handlers) board(q db.TaskQuery) (*boardResp, error) {
+	statuses := db.Statuses
+	if q.Status != "" {
+		statuses = []string{q.Status}
+	} else if q.Cursor != "" {
+		return nil, &validationError{field: "cursor", err: errors.New("cursor needs status")}
+	}
+	board := &boardResp{Columns: make([]*boardColumn, 0, len(statuses))}
+	for _, status := range statuses {
+		q.Status = status
+		page, err := h.listTasks(q)
+		if err != nil {
+			return nil, err
+		}
+		board.Columns = append(board.Columns, &boardColumn{Status: status, TaskPage: page})
+	}
+	return board, nil
+}
+
+// хэндлер смены статуса задачи: POST /api/task/status?id=&status=
+func (h *Handlers) TaskStatusHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.setStatus(req.FormValue("id"), req.FormValue("status"), "status"); err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, w)
+}
+
+// хэндлер доски: GET /api/board с теми же фильтрами, что и у /api/tasks; limit - задач в каждой колонке
+func (h *Handlers) BoardHandler(w http.ResponseWriter, req *http.Request) {
+	q, err := h.taskQuery(req, defTasks)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	board, err := h.board(q)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, board)
+}
+
+// хэндлер POST /api/v2/tasks/{id}/status: перенос задачи в колонку доски из джисона {"status": "done"}
+func (h *Handlers) SetStatusV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	var status jsonStatus
+	if _, ok := readJsonV2(w, req, &status); !ok {
+		return
+	}
+	if err := h.setStatus(strconv.Itoa(task.Id), status.Status, "status"); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, task.Id)
+}
+
+// хэндлер GET /api/v2/board: доска с теми же параметрами, что и у /api/board
+func (h *Handlers) BoardV2(w http.ResponseWriter, req *http.Request) {
+	q, err := h.taskQuery(req, defTasksV2)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	board, err := h.board(q)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, board)
+}
@@ -0,0 +1,260 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// максимальная длина текста пункта чек-листа в символах
+const maxItemText = 256
+
+// ошибка при выполнении задачи, у которой не все пункты чек-листа отмечены
+var errChecklistOpen = errors.New("task has open checklist items")
+
+// структура с пунктами чек-листа для вывода в джисоне
+type itemsResp struct {
+	Items []*db.ChecklistItem `json:"items"`
+}
+
+// функция проверки полей пункта: текст без пробелов по краям
+func checkItem(item *db.ChecklistItem) error {
+	item.Text = strings.TrimSpace(item.Text)
+	if item.Text == "" {
+		return &validationError{field: "text", err: errors.New("no text")}
+	}
+	if utf8.RuneCountInString(item.Text) > maxItemText {
+		return &validationError{field: "text", err: fmt.Errorf("text must be at most %d characters", maxItemText)}
+	}
+	if item.Position < 0 {
+		return &validationError{field: "position", err: errors.New("position must not be negative")}
+	}
+	return nil
+}
+
+// функция разбора параметра force, который разрешает выполнить задачу с неотмеченными пунктами
+func parseForce(req *http.Request) (bool, error) {
+	forceStr := req.FormValue("force")
+	if forceStr == "" {
+		return false, nil
+	}
+	force, err := strconv.ParseBool(forceStr)
+	if err != nil {
+		return false, &validationError{field: "force", err: errors.New("force must be true or false")}
+	}
+	return force, nil
+}
+
+// функция поиска пункта чек-листа задачи по айди
+func (h *Handlers) findItem(taskId, id int) (*db.ChecklistItem, error) {
+	items, err := h.store.ChecklistItems(taskId)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Id == id {
+			return item, nil
+		}
+	}
+	return nil, db.ErrItemNotFound
+}
+
+// функция проверки и добавления пункта в чек-лист задачи, в пункт записываются айди и место
+func (h *Handlers) createItem(taskId int, item *db.ChecklistItem) error {
+	item.TaskId = taskId
+	if err := checkItem(item); err != nil {
+		return err
+	}
+	id, err := h.store.AddChecklistItem(item)
+	if errors.Is(err, db.ErrItemNotFound) {
+		return &validationError{field: "parent_id", err: err}
+	}
+	if err != nil {
+		return err
+	}
+	added, err := h.findItem(taskId, int(id))
+	if err != nil {
+		return err
+	}
+	*item = *added
+	return nil
+}
+
+// функция сохранения пункта после изменения; айди, задача и вложенность берутся из прежнего пункта old
+func (h *Handlers) saveItem(item, old *db.ChecklistItem) error {
+	item.Id, item.TaskId, item.ParentId = old.Id, old.TaskId, old.ParentId
+	if err := checkItem(item); err != nil {
+		return err
+	}
+	return h.store.UpdChecklistItem(item)
+}
+
+// хэндлер чек-листа задачи ?id=: GET - пункты, POST - новый пункт, PUT - изменение пункта
+// (поля, которых нет в джисоне, остаются прежними), DELETE &item= - удаление пункта с вложенными
+func (h *Handlers) TaskChecklistHandler(w http.ResponseWriter, req *http.Request) {
+	task, err := h.store.GetTask(req.FormValue("id"))
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	var body []byte
+	var item db.ChecklistItem
+	if req.Method == http.MethodPost || req.Method == http.MethodPut {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(req.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = buf.Bytes()
+		if err := json.Unmarshal(body, &item); err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		items, err := h.store.ChecklistItems(task.Id)
+		if err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, itemsResp{Items: items})
+
+	case http.MethodPost:
+		if err := h.createItem(task.Id, &item); err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, item)
+
+	case http.MethodPut:
+		old, err := h.findItem(task.Id, item.Id)
+		if err == nil {
+			item = *old
+			err = json.Unmarshal(body, &item)
+		}
+		if err == nil {
+			err = h.saveItem(&item, old)
+		}
+		if err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, w)
+
+	case http.MethodDelete:
+		id, err := strconv.Atoi(req.FormValue("item"))
+		if err != nil {
+			writeJson(w, jsonError{ErrText: db.ErrItemNotFound.Error()})
+			return
+		}
+		if err := h.store.DelChecklistItem(task.Id, id); err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, w)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// функция чтения пункта чек-листа задачи из пути запроса; при ошибке отвечает и возвращает nil
+func (h *Handlers) pathItemV2(w http.ResponseWriter, req *http.Request, task *db.Task) *db.ChecklistItem {
+	id, err := strconv.Atoi(req.PathValue("item"))
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, codeNotFound, db.ErrItemNotFound.Error(), nil)
+		return nil
+	}
+	item, err := h.findItem(task.Id, id)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return nil
+	}
+	return item
+}
+
+// хэндлер GET /api/v2/tasks/{id}/checklist: пункты чек-листа задачи
+func (h *Handlers) ListChecklistV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	items, err := h.store.ChecklistItems(task.Id)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, itemsResp{Items: items})
+}
+
+// хэндлер POST /api/v2/tasks/{id}/checklist: новый пункт, отвечает 201 и адресом пункта
+func (h *Handlers) CreateItemV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	var item db.ChecklistItem
+	if _, ok := readJsonV2(w, req, &item); !ok {
+		return
+	}
+	if err := h.createItem(task.Id, &item); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v2/tasks/%d/checklist/%d", task.Id, item.Id))
+	writeJsonStatus(w, http.StatusCreated, item)
+}
+
+// хэндлер PUT /api/v2/tasks/{id}/checklist/{item}: изменение текста, отметки и места пункта;
+// поля, которых нет в джисоне, остаются прежними
+func (h *Handlers) UpdateItemV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	old := h.pathItemV2(w, req, task)
+	if old == nil {
+		return
+	}
+	item := *old
+	if _, ok := readJsonV2(w, req, &item); !ok {
+		return
+	}
+	if item.Id != old.Id {
+		writeApiError(w, http.StatusConflict, codeConflict, "item id in body differs from id in path",
+			map[string]any{"field": "id"})
+		return
+	}
+	if err := h.saveItem(&item, old); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, item)
+}
+
+// хэндлер DELETE /api/v2/tasks/{id}/checklist/{item}: удаление пункта вместе с вложенными
+func (h *Handlers) DeleteItemV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	item := h.pathItemV2(w, req, task)
+	if item == nil {
+		return
+	}
+	if err := h.store.DelChecklistItem(task.Id, item.Id); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
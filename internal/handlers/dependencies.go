@@ -0,0 +1,151 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// ошибка при выполнении задачи, которая ждет выполнения других задач
+var errTaskBlocked = errors.New("task is blocked by unfinished tasks")
+
+// структура для приема задачи, от которой зависит другая, в джисоне
+type jsonDependency struct {
+	DependsOn string `json:"depends_on"`
+}
+
+// функция разбора айди задачи, от которой зависит другая, из параметра или поля field
+func parseDependsOn(str, field string) (int, error) {
+	id, err := strconv.Atoi(str)
+	if err != nil || id < 1 {
+		return 0, &validationError{field: field, err: fmt.Errorf("%s must be a task id", field)}
+	}
+	return id, nil
+}
+
+// функция добавления зависимости задачи от задачи из параметра или поля field
+func (h *Handlers) addDependency(task *db.Task, onStr, field string) error {
+	dependsOn, err := parseDependsOn(onStr, field)
+	if err != nil {
+		return err
+	}
+	err = h.store.AddDependency(task.Id, dependsOn)
+	// сама задача уже прочитана, значит, нет той, от которой она должна зависеть
+	if errors.Is(err, db.ErrNotFound) {
+		return &validationError{field: field, err: err}
+	}
+	return err
+}
+
+// функция чтения цепочки зависимостей задачи со сроками выполнения
+func (h *Handlers) taskGraph(id int) (*db.Graph, error) {
+	graph, err := h.store.TaskGraph(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, tasks := range [][]*db.Task{graph.Upstream, graph.Downstream} {
+		for _, task := range tasks {
+			if err := h.cfg.Dates.SetDue(task); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return graph, nil
+}
+
+// хэндлер зависимостей задачи: POST /api/task/dependency?id=&on= - задача id ждет выполнения задачи on,
+// DELETE с теми же параметрами - больше не ждет
+func (h *Handlers) TaskDependencyHandler(w http.ResponseWriter, req *http.Request) {
+	task, err := h.store.GetTask(req.FormValue("id"))
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	switch req.Method {
+	case http.MethodPost:
+		err = h.addDependency(task, req.FormValue("on"), "on")
+	case http.MethodDelete:
+		var dependsOn int
+		if dependsOn, err = parseDependsOn(req.FormValue("on"), "on"); err == nil {
+			err = h.store.DelDependency(task.Id, dependsOn)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, w)
+}
+
+// хэндлер цепочки зависимостей задачи: GET /api/task/graph?id=
+func (h *Handlers) TaskGraphHandler(w http.ResponseWriter, req *http.Request) {
+	task, err := h.store.GetTask(req.FormValue("id"))
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	graph, err := h.taskGraph(task.Id)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, graph)
+}
+
+// хэндлер POST /api/v2/tasks/{id}/dependencies: задача ждет выполнения задачи из джисона {"depends_on": "2"};
+// отвечает задачей, а если зависимость замыкает цикл - 409
+func (h *Handlers) AddDependencyV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	var dep jsonDependency
+	if _, ok := readJsonV2(w, req, &dep); !ok {
+		return
+	}
+	if err := h.addDependency(task, dep.DependsOn, "depends_on"); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, task.Id)
+}
+
+// хэндлер DELETE /api/v2/tasks/{id}/dependencies/{dep}: задача больше не ждет задачу dep
+func (h *Handlers) DeleteDependencyV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	dependsOn, err := strconv.Atoi(req.PathValue("dep"))
+	if err == nil {
+		err = h.store.DelDependency(task.Id, dependsOn)
+	} else {
+		err = db.ErrDependencyNotFound
+	}
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// хэндлер GET /api/v2/tasks/{id}/graph: цепочка зависимостей задачи
+func (h *Handlers) TaskGraphV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	graph, err := h.taskGraph(task.Id)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, graph)
+}
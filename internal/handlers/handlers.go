@@ -4,15 +4,39 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/mrScorpio/finalTask/internal/config"
 	"github.com/mrScorpio/finalTask/internal/db"
 	"github.com/mrScorpio/finalTask/internal/nextdate"
 )
 
+// хэндлеры приложения вместе со всем, что им нужно для работы
+type Handlers struct {
+	store db.TaskStore
+	cfg   config.Config
+	clock nextdate.Clock
+	log   *log.Logger
+}
+
+// функция создания хэндлеров с хранилищем, настройками, часами и логом
+func New(store db.TaskStore, cfg config.Config, clock nextdate.Clock, logger *log.Logger) *Handlers {
+	if clock == nil {
+		clock = nextdate.SystemClock{}
+	}
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	return &Handlers{store: store, cfg: cfg, clock: clock, log: logger}
+}
+
 // структура для вывода текста ошибки в джисоне
 type jsonError struct {
 	ErrText string `json:"error"`
@@ -33,38 +57,101 @@ type taskResp struct {
 	Tasks []*db.Task `json:"tasks"`
 }
 
-// хэндлер проверки работы nextdate.NextDate(...)
-func NextDateHandler(w http.ResponseWriter, req *http.Request) {
+// хэндлер проверки работы nextdate.Settings.NextDate(...)
+func (h *Handlers) NextDateHandler(w http.ResponseWriter, req *http.Request) {
 
 	if req.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	curTmStr := req.FormValue("now")
+	curTm, err := h.parseNow(req.FormValue("now"), req.FormValue("tz"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var curTm time.Time
-	var err error
+	res, err := h.cfg.Dates.NextDate(curTm, req.FormValue("date"), req.FormValue("repeat"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// пустой ответ тоже текст
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(res))
+}
 
-	if curTmStr == "" {
-		curTm = time.Now()
-	} else {
-		curTm, err = time.Parse(db.TmFormat, curTmStr)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+// функция разбора текущего времени из запроса: дата 20060102 или время в формате RFC 3339;
+// если указан часовой пояс tz, текущий день определяется в нем
+func (h *Handlers) parseNow(now, tz string) (time.Time, error) {
+	loc, err := h.cfg.Dates.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if now == "" {
+		return h.clock.Now().In(loc), nil
+	}
+	tm, err := time.Parse(db.TmFormat, now)
+	if err != nil {
+		if tm, err = time.Parse(time.RFC3339, now); err != nil {
+			return time.Time{}, fmt.Errorf("now must be a date 20060102 or RFC 3339 time")
+		}
+	}
+	// время с часовым поясом переводим в пояс tz, дата без времени от пояса не зависит
+	if tz != "" {
+		tm = tm.In(loc)
+	}
+	return tm, nil
+}
+
+// количество дат в предпросмотре по умолчанию и максимальное
+const (
+	defOccurrences = 10
+	maxOccurrences = 100
+)
+
+// хэндлер предпросмотра следующих дат задачи по правилу повторения
+func (h *Handlers) OccurrencesHandler(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	curTm, err := h.parseNow(req.FormValue("now"), req.FormValue("tz"))
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	// количество дат
+	count := defOccurrences
+	if countStr := req.FormValue("count"); countStr != "" {
+		var err error
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 || count > maxOccurrences {
+			writeJson(w, jsonError{ErrText: fmt.Sprintf("count must be from 1 to %d", maxOccurrences)})
 			return
 		}
 	}
+	repeat := req.FormValue("repeat")
+	if repeat == "" {
+		writeJson(w, jsonError{ErrText: "no repeat rule"})
+		return
+	}
+	// без даты серия начинается сегодня
+	date := req.FormValue("date")
+	if date == "" {
+		date = curTm.Format(db.TmFormat)
+	}
 
-	res, err := nextdate.NextDate(curTm, req.FormValue("date"), req.FormValue("repeat"))
+	dates, err := h.cfg.Dates.Occurrences(curTm, date, repeat, count, req.FormValue("until"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeJson(w, jsonError{ErrText: err.Error()})
 		return
 	}
-	w.Write([]byte(res))
+	writeJson(w, dates)
 }
 
 // хэндлер обработки задачи
-func TaskHandler(w http.ResponseWriter, req *http.Request) {
+func (h *Handlers) TaskHandler(w http.ResponseWriter, req *http.Request) {
 	var task db.Task
 	var buf bytes.Buffer
 	// общие действия для пост- и пут-запросов
@@ -80,50 +167,41 @@ func TaskHandler(w http.ResponseWriter, req *http.Request) {
 			writeJson(w, jsonError{ErrText: err.Error()})
 			return
 		}
-		// проверили заголовок
-		if task.Title == "" {
-			writeJson(w, jsonError{ErrText: "no title"})
-			return
-		}
-		// проверили остальные поля
-		if err := nextdate.CheckDate(&task); err != nil {
-			writeJson(w, jsonError{ErrText: err.Error()})
-			return
-		}
 	}
 
 	switch req.Method {
 	case http.MethodPost:
 		// если пост-, то добавляем задачу в базу
-		id, err := db.AddTask(&task)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := h.createTask(&task); err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
 			return
 		}
-		task.Id = int(id)
 		writeJson(w, task)
 
 	case http.MethodGet:
 		//если гет-, то достаем из базы по айди
-		task, err := db.GetTask(req.FormValue("id"))
+		task, err := h.store.GetTask(req.FormValue("id"))
 		if err != nil {
 			writeJson(w, jsonError{ErrText: err.Error()})
 			return
 		}
+		if err := h.cfg.Dates.SetDue(task); err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
 		writeJson(w, task)
 
 	case http.MethodPut:
 		// если пут-, то изменяем запись в базе
-		err := db.UpdTask(&task)
-		if err != nil {
+		if err := h.updateTask(&task, buf.Bytes()); err != nil {
 			writeJson(w, jsonError{ErrText: err.Error()})
 			return
 		}
 		writeJson(w, w)
 
 	case http.MethodDelete:
-		// если делит-, то удаляем из базы
-		err := db.DelTask(req.FormValue("id"))
+		// если делит-, то переносим в корзину
+		err := h.trashTask(req.FormValue("id"))
 		if err != nil {
 			writeJson(w, jsonError{ErrText: err.Error()})
 			return
@@ -133,27 +211,272 @@ func TaskHandler(w http.ResponseWriter, req *http.Request) {
 
 }
 
-// хэндлер вывода списка задач из базы в джисон
-func TasksHandler(w http.ResponseWriter, req *http.Request) {
-	searchStr := req.FormValue("search")
-	limit := 66
-	var tasks []*db.Task
-	var err error
-	if len(searchStr) > 0 {
-		tasks, err = db.TasksSearchStr(limit, searchStr)
+// ошибка в полях задачи или параметрах запроса; field - поле, в котором ошибка, если оно известно
+type validationError struct {
+	field string
+	err   error
+}
+
+func (e *validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *validationError) Unwrap() error {
+	return e.err
+}
+
+// ошибка для разовой задачи, у которой нет повторений
+var errNotRepeating = errors.New("task doesn't repeat")
+
+// функция проверки полей задачи и актуализации ее даты
+func (h *Handlers) checkTask(task *db.Task) error {
+	if task.Title == "" {
+		return &validationError{field: "title", err: errors.New("no title")}
+	}
+	tags, err := db.NormalizeTags(task.Tags)
+	if err != nil {
+		return &validationError{field: "tags", err: err}
+	}
+	task.Tags = tags
+	if err := checkWorkflow(task); err != nil {
+		return err
+	}
+	// задача без списка попадает в список по умолчанию
+	if task.ListId == 0 {
+		task.ListId = db.DefaultList
+	} else if _, err := h.store.GetList(task.ListId); err != nil {
+		if errors.Is(err, db.ErrListNotFound) {
+			return &validationError{field: "list_id", err: err}
+		}
+		return err
+	}
+	if err := h.cfg.Dates.CheckDate(task, h.clock.Now()); err != nil {
+		var perr *nextdate.ParseError
+		if errors.As(err, &perr) {
+			return &validationError{field: "repeat", err: err}
+		}
+		return &validationError{err: err}
+	}
+	return nil
+}
+
+// функция проверки и добавления новой задачи в базу, в задачу записываются айди и срок
+func (h *Handlers) createTask(task *db.Task) error {
+	if err := h.checkTask(task); err != nil {
+		return err
+	}
+	// для правила с ограничением по количеству запоминаем, сколько раз выполнять задачу
+	if err := nextdate.SetRemaining(task); err != nil {
+		return &validationError{field: "repeat", err: err}
+	}
+	// по умолчанию задача повторяется по расписанию
+	if task.RepeatMode == "" {
+		task.RepeatMode = db.RepeatFixed
+	}
+	// время создания в UTC, чтобы задачи можно было упорядочить по нему как по строке
+	task.Created = h.clock.Now().UTC().Format(time.RFC3339)
+	id, err := h.store.AddTask(task)
+	if err != nil {
+		h.log.Printf("can't add task: %v", err)
+		return err
+	}
+	task.Id = int(id)
+	return h.cfg.Dates.SetDue(task)
+}
+
+// функция проверки и изменения задачи в базе, body - джисон запроса:
+// необязательные поля, которых в нем нет, остаются прежними
+func (h *Handlers) updateTask(task *db.Task, body []byte) error {
+	if task.Title == "" {
+		return &validationError{field: "title", err: errors.New("no title")}
+	}
+	old, err := h.store.GetTask(strconv.Itoa(task.Id))
+	if err != nil {
+		return err
+	}
+	if err := keepOmitted(task, old, body); err != nil {
+		return err
+	}
+	if err := h.checkTask(task); err != nil {
+		return err
+	}
+	// счетчик повторений сбрасываем, только если поменялось правило
+	if old.Repeat == task.Repeat {
+		task.Remaining = old.Remaining
+	} else if err := nextdate.SetRemaining(task); err != nil {
+		return &validationError{field: "repeat", err: err}
+	}
+	return h.store.UpdTask(task)
+}
+
+// функция переносит в задачу из старой записи необязательные поля, которых нет в джисоне запроса
+func keepOmitted(task, old *db.Task, body []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return err
+	}
+	if _, ok := fields["repeat_mode"]; !ok {
+		task.RepeatMode = old.RepeatMode
+	}
+	if _, ok := fields["time"]; !ok {
+		task.DueTime = old.DueTime
+	}
+	if _, ok := fields["timezone"]; !ok {
+		task.TimeZone = old.TimeZone
+	}
+	if _, ok := fields["tags"]; !ok {
+		task.Tags = old.Tags
+	}
+	if _, ok := fields["list_id"]; !ok {
+		task.ListId = old.ListId
+	}
+	if _, ok := fields["priority"]; !ok {
+		task.Priority = old.Priority
+	}
+	if _, ok := fields["status"]; !ok {
+		task.Status = old.Status
+	}
+	if _, ok := fields["estimate"]; !ok {
+		task.Estimate = old.Estimate
+	}
+	return nil
+}
+
+// количество задач на странице списка по умолчанию (столько показывает фронтенд) и максимальное
+const (
+	defTasks = 66
+	maxTasks = 500
+)
+
+// хэндлер вывода списка задач из базы в джисон: страница задач, курсор следующей страницы и количество всех задач
+func (h *Handlers) TasksHandler(w http.ResponseWriter, req *http.Request) {
+	q, err := h.taskQuery(req, defTasks)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	page, err := h.listTasks(q)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, page)
+}
+
+// функция разбора параметров списка задач: limit, cursor, sort (date, title, id, created, rank), order (asc, desc),
+// search, from и to (даты 20060102 включительно), has_repeat, overdue и blocked (true, false), tag (можно несколько),
+// list (айди списка), status (статус задачи), archived (true - задачи из архива)
+func (h *Handlers) taskQuery(req *http.Request, limit int) (db.TaskQuery, error) {
+	q := db.TaskQuery{
+		Limit:  limit,
+		Cursor: req.FormValue("cursor"),
+		Sort:   req.FormValue("sort"),
+		Search: req.FormValue("search"),
+		From:   req.FormValue("from"),
+		To:     req.FormValue("to"),
+	}
+	if limitStr := req.FormValue("limit"); limitStr != "" {
+		var err error
+		q.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || q.Limit < 1 || q.Limit > maxTasks {
+			return q, &validationError{field: "limit", err: fmt.Errorf("limit must be from 1 to %d", maxTasks)}
+		}
+	}
+	switch q.Sort {
+	case "", db.SortDate, db.SortTitle, db.SortId, db.SortCreated, db.SortRank:
+	default:
+		return q, &validationError{field: "sort", err: fmt.Errorf("sort must be %s, %s, %s, %s or %s",
+			db.SortDate, db.SortTitle, db.SortId, db.SortCreated, db.SortRank)}
+	}
+	switch req.FormValue("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, &validationError{field: "order", err: errors.New("order must be asc or desc")}
+	}
+	for field, date := range map[string]string{"from": q.From, "to": q.To} {
+		if _, err := time.Parse(db.TmFormat, date); date != "" && err != nil {
+			return q, &validationError{field: field, err: fmt.Errorf("%s must be a date 20060102", field)}
+		}
+	}
+	if listStr := req.FormValue("list"); listStr != "" {
+		var err error
+		if q.ListId, err = parseListId(listStr, "list"); err != nil {
+			return q, err
+		}
+	}
+	for _, tag := range req.URL.Query()["tag"] {
+		name, err := db.TagName(tag)
 		if err != nil {
-			writeJson(w, jsonError{ErrText: err.Error()})
-			return
+			return q, &validationError{field: "tag", err: err}
 		}
-	} else {
-		tasks, err = db.Tasks(limit)
+		q.Tags = append(q.Tags, name)
+	}
+	if repeatStr := req.FormValue("has_repeat"); repeatStr != "" {
+		hasRepeat, err := strconv.ParseBool(repeatStr)
 		if err != nil {
-			writeJson(w, jsonError{ErrText: err.Error()})
-			return
+			return q, &validationError{field: "has_repeat", err: errors.New("has_repeat must be true or false")}
+		}
+		q.HasRepeat = &hasRepeat
+	}
+	if archivedStr := req.FormValue("archived"); archivedStr != "" {
+		archived, err := strconv.ParseBool(archivedStr)
+		if err != nil {
+			return q, &validationError{field: "archived", err: errors.New("archived must be true or false")}
+		}
+		q.Archived = archived
+	}
+	if statusStr := req.FormValue("status"); statusStr != "" {
+		var err error
+		if q.Status, err = parseStatus(statusStr, "status"); err != nil {
+			return q, err
 		}
 	}
+	if blockedStr := req.FormValue("blocked"); blockedStr != "" {
+		blocked, err := strconv.ParseBool(blockedStr)
+		if err != nil {
+			return q, &validationError{field: "blocked", err: errors.New("blocked must be true or false")}
+		}
+		q.Blocked = &blocked
+	}
+	// просроченные задачи - с датой раньше сегодняшнего дня, поэтому фильтр сужает диапазон дат
+	if overdueStr := req.FormValue("overdue"); overdueStr != "" {
+		overdue, err := strconv.ParseBool(overdueStr)
+		if err != nil {
+			return q, &validationError{field: "overdue", err: errors.New("overdue must be true or false")}
+		}
+		now, err := h.parseNow("", "")
+		if err != nil {
+			return q, err
+		}
+		if today := now.Format(db.TmFormat); !overdue && q.From < today {
+			q.From = today
+		} else if yesterday := now.AddDate(0, 0, -1).Format(db.TmFormat); overdue && (q.To == "" || q.To > yesterday) {
+			q.To = yesterday
+		}
+	}
+	return q, nil
+}
 
-	writeJson(w, taskResp{Tasks: tasks})
+// функция чтения страницы списка задач со сроками
+func (h *Handlers) listTasks(q db.TaskQuery) (*db.TaskPage, error) {
+	page, err := h.store.ListTasks(q)
+	if errors.Is(err, db.ErrBadCursor) {
+		return nil, &validationError{field: "cursor", err: err}
+	}
+	if errors.Is(err, db.ErrBadSearch) {
+		return nil, &validationError{field: "search", err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range page.Tasks {
+		if err := h.cfg.Dates.SetDue(task); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
 }
 
 // функция вывода результатов работы хэндлеров в джисон-формате
@@ -165,55 +488,212 @@ func writeJson(w http.ResponseWriter, data any) {
 		return
 	}
 
+	// ошибка уходит со статусом 400, но остается джисоном
 	if _, ok := data.(jsonError); ok {
-		http.Error(w, "", http.StatusBadRequest)
+		w.WriteHeader(http.StatusBadRequest)
 	}
 
 	w.Write(resp)
 }
 
-// хэндлер обработки запроса о выполнении задачи
-func TaskDoneHandler(w http.ResponseWriter, req *http.Request) {
+// хэндлер обработки запроса о выполнении задачи; с неотмеченными пунктами чек-листа - только с force=true
+func (h *Handlers) TaskDoneHandler(w http.ResponseWriter, req *http.Request) {
+	force, err := parseForce(req)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
 	// зачитали задачу из базы
-	task, err := db.GetTask(req.FormValue("id"))
+	task, err := h.store.GetTask(req.FormValue("id"))
 	if err != nil {
 		writeJson(w, jsonError{ErrText: err.Error()})
 		return
 	}
-	// если нет правила повторения, то удаляем
-	if task.Repeat == "" {
-		err := db.DelTask(req.FormValue("id"))
-		if err != nil {
-			writeJson(w, jsonError{ErrText: err.Error()})
-			return
-		}
-		writeJson(w, w)
+	if err := h.completeTask(task, force); err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, w)
+}
+
+// хэндлер пропуска текущего повторения задачи
+func (h *Handlers) TaskSkipHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	// если правило есть, то анализируем дату
-	tm, err := time.Parse(db.TmFormat, task.Date)
+	task, err := h.store.GetTask(req.FormValue("id"))
 	if err != nil {
 		writeJson(w, jsonError{ErrText: err.Error()})
 		return
 	}
-	// рассчитываем новую дату
-	nxtdt, err := nextdate.NextDate(tm, task.Date, task.Repeat)
+	if err := h.skipTask(task); err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, w)
+}
+
+// хэндлер переноса текущего повторения задачи на другую дату, правило при этом не меняется
+func (h *Handlers) TaskRescheduleHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	date := req.FormValue("date")
+	if err := checkRescheduleDate(date); err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	task, err := h.store.GetTask(req.FormValue("id"))
 	if err != nil {
 		writeJson(w, jsonError{ErrText: err.Error()})
 		return
 	}
-	// и обновляем ее в базе
-	if err := db.UpDateTask(nxtdt, req.FormValue("id")); err != nil {
+	if err := h.rescheduleTask(task, date); err != nil {
 		writeJson(w, jsonError{ErrText: err.Error()})
 		return
 	}
 	writeJson(w, w)
 }
 
+// функция выполнения текущего повторения задачи; задача, которая ждет других задач, не выполняется,
+// а пока в чек-листе есть неотмеченные пункты, задача выполняется только с force;
+// выполнение записывается в историю вместе с переходом на следующее повторение;
+// зависимости остаются, но выполненное повторение больше никого не держит
+func (h *Handlers) completeTask(task *db.Task, force bool) error {
+	if task.Blocked {
+		return errTaskBlocked
+	}
+	if !force && task.Progress != nil && task.Progress.Done < task.Progress.Total {
+		return errChecklistOpen
+	}
+	// для отмены запоминаем, каким было повторение до выполнения
+	completion := &db.Completion{TaskId: task.Id, Date: task.Date, Origin: task.Date, Remaining: task.Remaining,
+		Status: task.Status, CompletedAt: h.clock.Now().UTC().Format(time.RFC3339)}
+	return h.advanceTask(task, completion)
+}
+
+// функция пропуска текущего повторения задачи; у разовой задачи пропускать нечего
+func (h *Handlers) skipTask(task *db.Task) error {
+	if task.Repeat == "" {
+		return errNotRepeating
+	}
+	return h.advanceTask(task, nil)
+}
+
+// функция проверки даты, на которую переносится повторение
+func checkRescheduleDate(date string) error {
+	if _, err := time.Parse(db.TmFormat, date); err != nil {
+		return &validationError{field: "date", err: errors.New("wrong date")}
+	}
+	return nil
+}
+
+// функция переноса текущего повторения задачи на дату date
+func (h *Handlers) rescheduleTask(task *db.Task, date string) error {
+	// повторение запоминаем по исходной дате серии, чтобы после выполнения серия продолжилась как раньше
+	if task.Repeat != "" {
+		exceptions, err := h.store.Exceptions(task.Id)
+		if err != nil {
+			return err
+		}
+		origin := occurrenceOrigin(task, exceptions)
+		// перенос обратно на исходную дату отменяет исключение
+		if date == origin {
+			err = h.store.DelException(task.Id, origin)
+		} else {
+			err = h.store.AddException(&db.Exception{TaskId: task.Id, Date: origin, NewDate: date})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return h.store.UpDateTask(date, task.Remaining, strconv.Itoa(task.Id))
+}
+
+// функция переводит задачу на следующую дату серии после выполнения (completion - запись для истории)
+// или пропуска (completion = nil) текущего повторения; разовая задача и задача, у которой серия закончилась,
+// уходят в архив; в режиме after-completion следующая дата считается от дня выполнения или пропуска;
+// отметки чек-листа снимаются, а задача возвращается в колонку StatusTodo; все это хранилище записывает вместе
+func (h *Handlers) advanceTask(task *db.Task, completion *db.Completion) error {
+	advance := &db.Advance{TaskId: task.Id, Completion: completion}
+	if task.Repeat == "" {
+		return h.archiveTask(advance)
+	}
+	exceptions, err := h.store.Exceptions(task.Id)
+	if err != nil {
+		return err
+	}
+	// следующую дату считаем от исходной даты повторения, даже если его переносили
+	origin := occurrenceOrigin(task, exceptions)
+	if completion != nil {
+		completion.Origin = origin
+	}
+	exdates := make([]string, 0, len(exceptions)+1)
+	for _, ex := range exceptions {
+		exdates = append(exdates, ex.Date)
+	}
+	// пропущенное или перенесенное повторение после выполнения остается в базе как обычное исключение
+	if completion == nil || origin != task.Date {
+		advance.Exception = origin
+		exdates = append(exdates, origin)
+	}
+	// если это было последнее повторение, то в архив
+	if task.Remaining == 1 {
+		return h.archiveTask(advance)
+	}
+	// в режиме after-completion серия отсчитывается заново от сегодняшнего дня
+	from := origin
+	if task.RepeatMode == db.RepeatAfterCompletion {
+		now, err := h.cfg.Dates.TaskNow(task, h.clock.Now())
+		if err != nil {
+			return err
+		}
+		from = now.Format(db.TmFormat)
+	}
+	// рассчитываем новую дату
+	tm, err := time.Parse(db.TmFormat, from)
+	if err != nil {
+		return err
+	}
+	nxtdt, err := h.cfg.Dates.NextDate(tm, from, task.Repeat, exdates...)
+	if err != nil {
+		return err
+	}
+	// если серия по правилу закончилась, то задача больше не нужна
+	if nxtdt == "" {
+		return h.archiveTask(advance)
+	}
+	// уменьшаем счетчик оставшихся повторений
+	advance.Date, advance.Remaining = nxtdt, task.Remaining
+	if advance.Remaining > 0 {
+		advance.Remaining--
+	}
+	// и обновляем ее в базе, чек-лист нового повторения проходится заново
+	return h.store.AdvanceTask(advance)
+}
+
+// функция переноса задачи в архив с текущим временем
+func (h *Handlers) archiveTask(advance *db.Advance) error {
+	advance.Archived = h.clock.Now().UTC().Format(time.RFC3339)
+	return h.store.AdvanceTask(advance)
+}
+
+// функция возвращает исходную дату текущего повторения задачи: если оно перенесено, то дату до переноса
+func occurrenceOrigin(task *db.Task, exceptions []*db.Exception) string {
+	for _, ex := range exceptions {
+		if ex.NewDate != "" && ex.NewDate == task.Date {
+			return ex.Date
+		}
+	}
+	return task.Date
+}
+
 // функция проверки пароля
-func ChkPass(w http.ResponseWriter, req *http.Request) {
+func (h *Handlers) ChkPass(w http.ResponseWriter, req *http.Request) {
 	var buf bytes.Buffer
-	myPass := os.Getenv("TODO_PASSWORD")
+	myPass := h.cfg.Password
 	// проверили что пароль задан
 	if len(myPass) < 1 {
 		return
@@ -252,33 +732,32 @@ func ChkPass(w http.ResponseWriter, req *http.Request) {
 }
 
 // функция аутентификации
-func Auth(next http.HandlerFunc) http.HandlerFunc {
+func (h *Handlers) Auth(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// смотрим наличие пароля
-		pass := os.Getenv("TODO_PASSWORD")
-		if len(pass) > 0 {
-			var jwtSigned string // JWT-токен из куки
-			// получаем куку
-			cookie, err := r.Cookie("token")
-			if err == nil {
-				jwtSigned = cookie.Value
-			} else {
-				http.Error(w, "Authentification required", http.StatusUnauthorized)
-				return
-			}
-
-			// здесь код для валидации и проверки JWT-токена
-			jwtToken, jwtErr := jwt.Parse(jwtSigned, func(t *jwt.Token) (interface{}, error) {
-				// секретный ключ для всех токенов одинаковый, поэтому просто возвращаем его
-				return []byte(pass), nil
-			})
-
-			if !jwtToken.Valid || jwtErr != nil {
-				// возвращаем ошибку авторизации 401
-				http.Error(w, "Authentification required", http.StatusUnauthorized)
-				return
-			}
+		if !h.authorized(r) {
+			// возвращаем ошибку авторизации 401
+			http.Error(w, "Authentification required", http.StatusUnauthorized)
+			return
 		}
 		next(w, r)
 	})
 }
+
+// функция проверки JWT-токена из куки, если задан пароль
+func (h *Handlers) authorized(r *http.Request) bool {
+	// смотрим наличие пароля
+	pass := h.cfg.Password
+	if len(pass) == 0 {
+		return true
+	}
+	// получаем куку с JWT-токеном
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		return false
+	}
+	jwtToken, err := jwt.Parse(cookie.Value, func(t *jwt.Token) (interface{}, error) {
+		// секретный ключ для всех токенов одинаковый, поэтому просто возвращаем его
+		return []byte(pass), nil
+	})
+	return err == nil && jwtToken.Valid
+}
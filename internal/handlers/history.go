@@ -0,0 +1,106 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// количество выполнений в истории по умолчанию и максимальное
+const (
+	defHistory = 100
+	maxHistory = 1000
+)
+
+// структура с историей выполнений для вывода в джисоне
+type historyResp struct {
+	Completions []*db.Completion `json:"completions"`
+}
+
+// функция чтения истории выполнений с параметрами from и to (даты повторений 20060102 включительно) и limit
+func (h *Handlers) history(req *http.Request) (*historyResp, error) {
+	from, to := req.FormValue("from"), req.FormValue("to")
+	for field, date := range map[string]string{"from": from, "to": to} {
+		if _, err := time.Parse(db.TmFormat, date); date != "" && err != nil {
+			return nil, &validationError{field: field, err: fmt.Errorf("%s must be a date 20060102", field)}
+		}
+	}
+	limit := defHistory
+	if limitStr := req.FormValue("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxHistory {
+			return nil, &validationError{field: "limit", err: fmt.Errorf("limit must be from 1 to %d", maxHistory)}
+		}
+	}
+	completions, err := h.store.Completions(from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &historyResp{Completions: completions}, nil
+}
+
+// функция отмены последнего выполнения: задача возвращается на дату выполненного повторения
+// (и из архива, если ушла туда) с прежними колонкой и отметками чек-листа, а перенос этого повторения
+// восстанавливается; возвращает айди задачи
+func (h *Handlers) undoCompletion() (int, error) {
+	c, err := h.store.LastCompletion()
+	if err != nil {
+		return 0, err
+	}
+	if err := h.store.UndoCompletion(c); err != nil {
+		return 0, err
+	}
+	return c.TaskId, nil
+}
+
+// хэндлер истории выполнений: GET /api/history?from=&to=&limit=, сначала последние выполнения
+func (h *Handlers) HistoryHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	history, err := h.history(req)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, history)
+}
+
+// хэндлер отмены последнего выполнения: POST /api/history/undo
+func (h *Handlers) HistoryUndoHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := h.undoCompletion(); err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, w)
+}
+
+// хэндлер GET /api/v2/history: история выполнений с теми же параметрами, что и у /api/history
+func (h *Handlers) HistoryV2(w http.ResponseWriter, req *http.Request) {
+	history, err := h.history(req)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, history)
+}
+
+// хэндлер POST /api/v2/history/undo: отмена последнего выполнения, отвечает восстановленной задачей
+func (h *Handlers) UndoCompletionV2(w http.ResponseWriter, req *http.Request) {
+	id, err := h.undoCompletion()
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, id)
+}
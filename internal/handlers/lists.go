@@ -0,0 +1,319 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// максимальная длина имени списка в символах
+const maxListName = 64
+
+// цвет списка в виде #rrggbb
+var listColour = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// структура со списками задач для вывода в джисоне
+type listsResp struct {
+	Lists []*db.List `json:"lists"`
+}
+
+// структура для приема списка, в который переносится задача, в джисоне
+type jsonMove struct {
+	ListId string `json:"list_id"`
+}
+
+// функция разбора айди списка из параметра или поля field
+func parseListId(str, field string) (int, error) {
+	id, err := strconv.Atoi(str)
+	if err != nil || id < 1 {
+		return 0, &validationError{field: field, err: fmt.Errorf("%s must be a list id", field)}
+	}
+	return id, nil
+}
+
+// функция проверки полей списка: имя без пробелов по краям, цвет в нижнем регистре
+func checkList(list *db.List) error {
+	list.Name = strings.TrimSpace(list.Name)
+	if list.Name == "" {
+		return &validationError{field: "name", err: errors.New("no name")}
+	}
+	if utf8.RuneCountInString(list.Name) > maxListName {
+		return &validationError{field: "name", err: fmt.Errorf("name must be at most %d characters", maxListName)}
+	}
+	list.Colour = strings.ToLower(list.Colour)
+	if list.Colour != "" && !listColour.MatchString(list.Colour) {
+		return &validationError{field: "colour", err: errors.New("colour must look like #rrggbb")}
+	}
+	if list.Position < 0 {
+		return &validationError{field: "position", err: errors.New("position must not be negative")}
+	}
+	return nil
+}
+
+// функция разбора параметров удаления списка id: tasks=move (по умолчанию) переносит задачи в список to
+// (по умолчанию - в список по умолчанию), tasks=cascade переносит их в корзину; возвращает айди для DelList
+func (h *Handlers) deleteListTo(req *http.Request, id int) (int, error) {
+	if id == db.DefaultList {
+		return 0, db.ErrDefaultList
+	}
+	switch req.FormValue("tasks") {
+	case "", "move":
+	case "cascade":
+		return 0, nil
+	default:
+		return 0, &validationError{field: "tasks", err: errors.New("tasks must be move or cascade")}
+	}
+	moveTo := db.DefaultList
+	if toStr := req.FormValue("to"); toStr != "" {
+		var err error
+		if moveTo, err = parseListId(toStr, "to"); err != nil {
+			return 0, err
+		}
+	}
+	if moveTo == id {
+		return 0, &validationError{field: "to", err: errors.New("tasks can't be moved to the deleted list")}
+	}
+	if _, err := h.store.GetList(moveTo); errors.Is(err, db.ErrListNotFound) {
+		return 0, &validationError{field: "to", err: err}
+	} else if err != nil {
+		return 0, err
+	}
+	return moveTo, nil
+}
+
+// функция проверки и добавления нового списка, в список записываются айди и место
+func (h *Handlers) createList(list *db.List) error {
+	if err := checkList(list); err != nil {
+		return err
+	}
+	id, err := h.store.AddList(list)
+	if err != nil {
+		return err
+	}
+	added, err := h.store.GetList(int(id))
+	if err != nil {
+		return err
+	}
+	*list = *added
+	return nil
+}
+
+// функция переноса задачи в список; айди списка проверяется хранилищем
+func (h *Handlers) moveTask(id string, listStr, field string) error {
+	listId, err := parseListId(listStr, field)
+	if err != nil {
+		return err
+	}
+	err = h.store.MoveTask(id, listId)
+	if errors.Is(err, db.ErrListNotFound) {
+		return &validationError{field: field, err: err}
+	}
+	return err
+}
+
+// хэндлер вывода всех списков с количеством задач
+func (h *Handlers) ListsHandler(w http.ResponseWriter, req *http.Request) {
+	lists, err := h.store.Lists()
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, listsResp{Lists: lists})
+}
+
+// хэндлер обработки списка: POST - новый, GET ?id= - список, PUT - изменение, DELETE ?id=&tasks=&to= - удаление
+func (h *Handlers) ListHandler(w http.ResponseWriter, req *http.Request) {
+	var list db.List
+	if req.Method == http.MethodPost || req.Method == http.MethodPut {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(req.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := json.Unmarshal(buf.Bytes(), &list); err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		if err := h.createList(&list); err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, list)
+
+	case http.MethodGet:
+		id, err := parseListId(req.FormValue("id"), "id")
+		if err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		found, err := h.store.GetList(id)
+		if err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, found)
+
+	case http.MethodPut:
+		err := checkList(&list)
+		if err == nil {
+			err = h.store.UpdList(&list)
+		}
+		if err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, w)
+
+	case http.MethodDelete:
+		id, err := parseListId(req.FormValue("id"), "id")
+		if err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		moveTo, err := h.deleteListTo(req, id)
+		if err == nil {
+			err = h.store.DelList(id, moveTo, h.clock.Now().UTC().Format(time.RFC3339))
+		}
+		if err != nil {
+			writeJson(w, jsonError{ErrText: err.Error()})
+			return
+		}
+		writeJson(w, w)
+	}
+}
+
+// хэндлер переноса задачи в другой список: POST /api/task/move?id=&list=
+func (h *Handlers) TaskMoveHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.moveTask(req.FormValue("id"), req.FormValue("list"), "list"); err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, w)
+}
+
+// функция чтения айди списка из пути запроса; айди, который не является числом, дает 404
+func pathListV2(w http.ResponseWriter, req *http.Request) (int, bool) {
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, codeNotFound, db.ErrListNotFound.Error(), nil)
+		return 0, false
+	}
+	return id, true
+}
+
+// хэндлер GET /api/v2/lists: все списки с количеством задач
+func (h *Handlers) ListListsV2(w http.ResponseWriter, req *http.Request) {
+	lists, err := h.store.Lists()
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, listsResp{Lists: lists})
+}
+
+// хэндлер POST /api/v2/lists: новый список, отвечает 201 и адресом списка
+func (h *Handlers) CreateListV2(w http.ResponseWriter, req *http.Request) {
+	var list db.List
+	if _, ok := readJsonV2(w, req, &list); !ok {
+		return
+	}
+	if err := h.createList(&list); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v2/lists/%d", list.Id))
+	writeJsonStatus(w, http.StatusCreated, list)
+}
+
+// хэндлер GET /api/v2/lists/{id}
+func (h *Handlers) GetListV2(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathListV2(w, req)
+	if !ok {
+		return
+	}
+	list, err := h.store.GetList(id)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, list)
+}
+
+// хэндлер PUT /api/v2/lists/{id}: изменение имени, цвета и места списка, отвечает списком после изменения
+func (h *Handlers) UpdateListV2(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathListV2(w, req)
+	if !ok {
+		return
+	}
+	var list db.List
+	if _, ok := readJsonV2(w, req, &list); !ok {
+		return
+	}
+	if list.Id != 0 && list.Id != id {
+		writeApiError(w, http.StatusConflict, codeConflict, "list id in body differs from id in path",
+			map[string]any{"field": "id"})
+		return
+	}
+	list.Id = id
+	err := checkList(&list)
+	if err == nil {
+		err = h.store.UpdList(&list)
+	}
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.GetListV2(w, req)
+}
+
+// хэндлер DELETE /api/v2/lists/{id}?tasks=move|cascade&to=: удаление списка с переносом задач или в корзину
+func (h *Handlers) DeleteListV2(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathListV2(w, req)
+	if !ok {
+		return
+	}
+	moveTo, err := h.deleteListTo(req, id)
+	if err == nil {
+		err = h.store.DelList(id, moveTo, h.clock.Now().UTC().Format(time.RFC3339))
+	}
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// хэндлер POST /api/v2/tasks/{id}/move: перенос задачи в список из джисона {"list_id": "2"}
+func (h *Handlers) MoveTaskV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	var move jsonMove
+	if _, ok := readJsonV2(w, req, &move); !ok {
+		return
+	}
+	if err := h.moveTask(strconv.Itoa(task.Id), move.ListId, "list_id"); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, task.Id)
+}
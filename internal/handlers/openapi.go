@@ -0,0 +1,22 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// описание API в формате OpenAPI 3; при изменении хэндлеров его нужно обновлять вместе с ними
+//
+//go:embed openapi.json
+var openapiSpec []byte
+
+// хэндлер выдачи описания API
+func (h *Handlers) OpenAPIHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Write(openapiSpec)
+}
@@ -0,0 +1,119 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// структура со списком меток для вывода в джисоне
+type tagsResp struct {
+	Tags []*db.Tag `json:"tags"`
+}
+
+// структура для приема нового имени метки в джисоне
+type jsonTagName struct {
+	Name string `json:"name"`
+}
+
+// структура для приема метки, с которой объединяется другая, в джисоне
+type jsonTagInto struct {
+	Into string `json:"into"`
+}
+
+// хэндлер облака меток: все метки с количеством задач, сначала самые частые
+func (h *Handlers) TagsHandler(w http.ResponseWriter, req *http.Request) {
+	tags, err := h.store.Tags()
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, tagsResp{Tags: tags})
+}
+
+// функция чтения имени метки из пути запроса; имя, которое не может быть у метки, дает 404
+func pathTagV2(w http.ResponseWriter, req *http.Request) (string, bool) {
+	name, err := db.TagName(req.PathValue("name"))
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, codeNotFound, db.ErrTagNotFound.Error(), nil)
+		return "", false
+	}
+	return name, true
+}
+
+// функция проверки имени метки из тела запроса, field - поле джисона
+func bodyTagName(name, field string) (string, error) {
+	name, err := db.TagName(name)
+	if err != nil {
+		return "", &validationError{field: field, err: err}
+	}
+	return name, nil
+}
+
+// хэндлер GET /api/v2/tags: облако меток
+func (h *Handlers) ListTagsV2(w http.ResponseWriter, req *http.Request) {
+	tags, err := h.store.Tags()
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, tagsResp{Tags: tags})
+}
+
+// хэндлер PUT /api/v2/tags/{name}: переименование метки из джисона {"name": "новое"}, для занятого имени 409
+func (h *Handlers) RenameTagV2(w http.ResponseWriter, req *http.Request) {
+	name, ok := pathTagV2(w, req)
+	if !ok {
+		return
+	}
+	var body jsonTagName
+	if _, ok := readJsonV2(w, req, &body); !ok {
+		return
+	}
+	var tag *db.Tag
+	newName, err := bodyTagName(body.Name, "name")
+	if err == nil {
+		tag, err = h.store.RenameTag(name, newName)
+	}
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, tag)
+}
+
+// хэндлер POST /api/v2/tags/{name}/merge: перенос задач на метку из джисона {"into": "метка"}, отвечает ею
+func (h *Handlers) MergeTagV2(w http.ResponseWriter, req *http.Request) {
+	name, ok := pathTagV2(w, req)
+	if !ok {
+		return
+	}
+	var body jsonTagInto
+	if _, ok := readJsonV2(w, req, &body); !ok {
+		return
+	}
+	var tag *db.Tag
+	into, err := bodyTagName(body.Into, "into")
+	if err == nil {
+		tag, err = h.store.MergeTag(name, into)
+	}
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, tag)
+}
+
+// хэндлер DELETE /api/v2/tags/{name}: метка снимается со всех задач
+func (h *Handlers) DeleteTagV2(w http.ResponseWriter, req *http.Request) {
+	name, ok := pathTagV2(w, req)
+	if !ok {
+		return
+	}
+	if err := h.store.DelTag(name); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// количество задач из корзины в ответе по умолчанию и максимальное
+const (
+	defTrash = 100
+	maxTrash = 1000
+)
+
+// функция переноса задачи в корзину с текущим временем
+func (h *Handlers) trashTask(id string) error {
+	return h.store.TrashTask(id, h.clock.Now().UTC().Format(time.RFC3339))
+}
+
+// функция чтения задач из корзины с параметром limit
+func (h *Handlers) trash(req *http.Request) (*taskResp, error) {
+	limit := defTrash
+	if limitStr := req.FormValue("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxTrash {
+			return nil, &validationError{field: "limit", err: fmt.Errorf("limit must be from 1 to %d", maxTrash)}
+		}
+	}
+	tasks, err := h.store.Trash(limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if err := h.cfg.Dates.SetDue(task); err != nil {
+			return nil, err
+		}
+	}
+	return &taskResp{Tasks: tasks}, nil
+}
+
+// функция окончательного удаления задач, которые пролежали в корзине дольше retention;
+// возвращает количество удаленных задач
+func (h *Handlers) PurgeTrash(retention time.Duration) (int, error) {
+	return h.store.PurgeTrash(h.clock.Now().Add(-retention).UTC().Format(time.RFC3339))
+}
+
+// функция фоновой очистки корзины: сразу после запуска и затем каждые interval, пока не закрыт stop
+func (h *Handlers) RunPurger(retention, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := h.PurgeTrash(retention)
+		if err != nil {
+			h.log.Printf("can't purge trash: %v", err)
+		} else if purged > 0 {
+			h.log.Printf("purged %d tasks from trash", purged)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// хэндлер корзины: GET /api/trash?limit= - задачи из корзины, сначала последние удаленные
+func (h *Handlers) TrashHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	trash, err := h.trash(req)
+	if err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, trash)
+}
+
+// хэндлер возврата задачи из корзины: POST /api/trash/restore?id=
+func (h *Handlers) TrashRestoreHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.store.RestoreTask(req.FormValue("id")); err != nil {
+		writeJson(w, jsonError{ErrText: err.Error()})
+		return
+	}
+	writeJson(w, w)
+}
+
+// хэндлер GET /api/v2/trash: задачи из корзины с теми же параметрами, что и у /api/trash
+func (h *Handlers) ListTrashV2(w http.ResponseWriter, req *http.Request) {
+	trash, err := h.trash(req)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, trash)
+}
+
+// хэндлер POST /api/v2/trash/{id}/restore: возврат задачи из корзины, отвечает задачей
+// (или 204, если задача в архиве) либо 404, если в корзине ее нет
+func (h *Handlers) RestoreTaskV2(w http.ResponseWriter, req *http.Request) {
+	// айди, который не является числом, не может принадлежать задаче
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil {
+		writeApiError(w, http.StatusNotFound, codeNotFound, db.ErrNotFound.Error(), nil)
+		return
+	}
+	if err := h.store.RestoreTask(strconv.Itoa(id)); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, id)
+}
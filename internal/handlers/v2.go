@@ -0,0 +1,279 @@
+// пакет с хэндлерами хттп-запросов
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/mrScorpio/finalTask/internal/nextdate"
+)
+
+// коды ошибок API v2
+const (
+	codeBadRequest   = "bad_request"       // запрос не удалось разобрать
+	codeUnauthorized = "unauthorized"      // нет действующего токена
+	codeNotFound     = "not_found"         // задачи, метки, списка, пункта чек-листа, зависимости или выполнения нет
+	codeConflict     = "conflict"          // действие противоречит состоянию задачи, метки или списка
+	codeValidation   = "validation_failed" // ошибка в полях задачи или параметрах запроса
+	codeInternal     = "internal"          // ошибка сервера или БД
+)
+
+// количество задач на странице списка API v2 по умолчанию
+const defTasksV2 = 50
+
+// структура ошибки API v2
+type apiError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// структура для приема даты переноса повторения в джисоне
+type jsonDate struct {
+	Date string `json:"date"`
+}
+
+// функция вывода ответа API v2 с заданным статусом
+func writeJsonStatus(w http.ResponseWriter, status int, data any) {
+	resp, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+// функция вывода ошибки API v2
+func writeApiError(w http.ResponseWriter, status int, code, message string, details map[string]any) {
+	writeJsonStatus(w, status, apiError{Code: code, Message: message, Details: details})
+}
+
+// функция выбирает статус и код ответа по ошибке; ошибки сервера пишутся в лог, а клиенту уходит только общий текст
+func (h *Handlers) writeErrorV2(w http.ResponseWriter, err error) {
+	var verr *validationError
+	switch {
+	case errors.As(err, &verr):
+		details := map[string]any{}
+		if verr.field != "" {
+			details["field"] = verr.field
+		}
+		// для ошибки в правиле повторения показываем, где она
+		var perr *nextdate.ParseError
+		if errors.As(err, &perr) {
+			details["token"] = perr.Token
+			details["position"] = perr.Pos
+		}
+		// для ошибки в строке поиска - неверное условие
+		var serr *db.SearchError
+		if errors.As(err, &serr) {
+			details["token"] = serr.Clause
+			details["position"] = serr.Pos
+		}
+		writeApiError(w, http.StatusUnprocessableEntity, codeValidation, err.Error(), details)
+	case errors.Is(err, db.ErrNotFound), errors.Is(err, db.ErrTagNotFound), errors.Is(err, db.ErrListNotFound),
+		errors.Is(err, db.ErrItemNotFound), errors.Is(err, db.ErrDependencyNotFound), errors.Is(err, db.ErrCompletionNotFound):
+		writeApiError(w, http.StatusNotFound, codeNotFound, err.Error(), nil)
+	case errors.Is(err, errNotRepeating), errors.Is(err, db.ErrTagExists), errors.Is(err, db.ErrListExists),
+		errors.Is(err, db.ErrDefaultList), errors.Is(err, errChecklistOpen), errors.Is(err, errTaskBlocked),
+		errors.Is(err, db.ErrDependencyCycle):
+		writeApiError(w, http.StatusConflict, codeConflict, err.Error(), nil)
+	default:
+		h.log.Printf("api v2: %v", err)
+		writeApiError(w, http.StatusInternalServerError, codeInternal, "internal server error", nil)
+	}
+}
+
+// функция аутентификации для API v2, ошибка отдается в джисоне
+func (h *Handlers) AuthV2(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(r) {
+			writeApiError(w, http.StatusUnauthorized, codeUnauthorized, "authentication required", nil)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// функция чтения джисона из тела запроса; при ошибке отвечает 400 и возвращает false
+func readJsonV2(w http.ResponseWriter, req *http.Request, v any) ([]byte, bool) {
+	body, err := io.ReadAll(req.Body)
+	if err == nil {
+		err = json.Unmarshal(body, v)
+	}
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+		return nil, false
+	}
+	return body, true
+}
+
+// функция чтения задачи по айди из пути запроса; при ошибке отвечает и возвращает nil
+func (h *Handlers) pathTaskV2(w http.ResponseWriter, req *http.Request) *db.Task {
+	// айди, который не является числом, не может принадлежать задаче
+	if _, err := strconv.Atoi(req.PathValue("id")); err != nil {
+		writeApiError(w, http.StatusNotFound, codeNotFound, db.ErrNotFound.Error(), nil)
+		return nil
+	}
+	task, err := h.store.GetTask(req.PathValue("id"))
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return nil
+	}
+	return task
+}
+
+// функция ответа текущим состоянием задачи после изменения; если задачи больше нет, отвечает 204
+func (h *Handlers) writeTaskV2(w http.ResponseWriter, id int) {
+	task, err := h.store.GetTask(strconv.Itoa(id))
+	if errors.Is(err, db.ErrNotFound) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err == nil {
+		err = h.cfg.Dates.SetDue(task)
+	}
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, task)
+}
+
+// хэндлер GET /api/v2/tasks: страница списка задач с теми же параметрами, что и у /api/tasks
+func (h *Handlers) ListTasksV2(w http.ResponseWriter, req *http.Request) {
+	q, err := h.taskQuery(req, defTasksV2)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	page, err := h.listTasks(q)
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, page)
+}
+
+// хэндлер POST /api/v2/tasks: новая задача, отвечает 201 и адресом задачи
+func (h *Handlers) CreateTaskV2(w http.ResponseWriter, req *http.Request) {
+	var task db.Task
+	if _, ok := readJsonV2(w, req, &task); !ok {
+		return
+	}
+	if err := h.createTask(&task); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v2/tasks/%d", task.Id))
+	writeJsonStatus(w, http.StatusCreated, task)
+}
+
+// хэндлер GET /api/v2/tasks/{id}
+func (h *Handlers) GetTaskV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	if err := h.cfg.Dates.SetDue(task); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	writeJsonStatus(w, http.StatusOK, task)
+}
+
+// хэндлер PUT /api/v2/tasks/{id}: изменение задачи, отвечает задачей после изменения
+func (h *Handlers) UpdateTaskV2(w http.ResponseWriter, req *http.Request) {
+	old := h.pathTaskV2(w, req)
+	if old == nil {
+		return
+	}
+	var task db.Task
+	body, ok := readJsonV2(w, req, &task)
+	if !ok {
+		return
+	}
+	// айди в теле не обязателен, но если он есть, то должен совпадать с айди в пути
+	if task.Id != 0 && task.Id != old.Id {
+		writeApiError(w, http.StatusConflict, codeConflict, "task id in body differs from id in path",
+			map[string]any{"field": "id"})
+		return
+	}
+	task.Id = old.Id
+	if err := h.updateTask(&task, body); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, task.Id)
+}
+
+// хэндлер DELETE /api/v2/tasks/{id}: перенос задачи в корзину
+func (h *Handlers) DeleteTaskV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	if err := h.trashTask(strconv.Itoa(task.Id)); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// хэндлер POST /api/v2/tasks/{id}/complete?force=: выполнение текущего повторения;
+// отвечает задачей со следующей датой или 204, если задача ушла в архив, а при неотмеченных пунктах чек-листа без force - 409
+func (h *Handlers) CompleteTaskV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	force, err := parseForce(req)
+	if err == nil {
+		err = h.completeTask(task, force)
+	}
+	if err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, task.Id)
+}
+
+// хэндлер POST /api/v2/tasks/{id}/skip: пропуск текущего повторения, для разовой задачи 409
+func (h *Handlers) SkipTaskV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	if err := h.skipTask(task); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, task.Id)
+}
+
+// хэндлер POST /api/v2/tasks/{id}/reschedule: перенос текущего повторения на дату из джисона {"date": "20060102"}
+func (h *Handlers) RescheduleTaskV2(w http.ResponseWriter, req *http.Request) {
+	task := h.pathTaskV2(w, req)
+	if task == nil {
+		return
+	}
+	var date jsonDate
+	if _, ok := readJsonV2(w, req, &date); !ok {
+		return
+	}
+	if err := checkRescheduleDate(date.Date); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	if err := h.rescheduleTask(task, date.Date); err != nil {
+		h.writeErrorV2(w, err)
+		return
+	}
+	h.writeTaskV2(w, task.Id)
+}
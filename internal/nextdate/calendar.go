@@ -0,0 +1,214 @@
+// пакет расчета следующей даты при изменении записи
+package nextdate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// сколько нерабочих дней подряд просматриваем в поисках рабочего, чтобы не зациклиться на странном календаре
+const maxDaysOff = 366
+
+// производственный календарь, по которому определяются рабочие дни
+type Calendar interface {
+	IsWorkday(date time.Time) bool
+}
+
+// календарь по умолчанию: суббота и воскресенье - выходные
+type Weekends struct{}
+
+func (Weekends) IsWorkday(date time.Time) bool {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// календарь с праздниками и перенесенными рабочими днями поверх обычных выходных
+type HolidayCalendar struct {
+	holidays map[string]bool
+	workdays map[string]bool
+	events   []holidayEvent
+}
+
+// повторяющийся праздник из ICS-файла
+type holidayEvent struct {
+	rule Rule
+	days int // продолжительность в днях
+}
+
+func (c *HolidayCalendar) IsWorkday(date time.Time) bool {
+	key := date.Format(db.TmFormat)
+	if c.workdays[key] {
+		return true
+	}
+	if c.holidays[key] {
+		return false
+	}
+	date = dateOf(date)
+	for _, ev := range c.events {
+		// дата праздничная, если она попадает в одно из повторений события
+		for i := 0; i < ev.days; i++ {
+			day := date.AddDate(0, 0, -i)
+			if next, ok := ev.rule.Next(day.AddDate(0, 0, -1)); ok && next.Equal(day) {
+				return false
+			}
+		}
+	}
+	return Weekends{}.IsWorkday(date)
+}
+
+// функция загрузки календаря праздников из файлов .ics и .json
+func LoadCalendar(files ...string) (*HolidayCalendar, error) {
+	cal := HolidayCalendar{holidays: make(map[string]bool), workdays: make(map[string]bool)}
+	for _, file := range files {
+		var err error
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".ics":
+			err = cal.loadICS(file)
+		case ".json":
+			err = cal.loadJSON(file)
+		default:
+			err = fmt.Errorf("unknown calendar format")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't load holidays from %s: %w", file, err)
+		}
+	}
+	return &cal, nil
+}
+
+// структура файла календаря в джисоне: даты в формате 20060102
+type calendarFile struct {
+	Holidays []string `json:"holidays"`
+	Workdays []string `json:"workdays"`
+}
+
+// функция загрузки праздников и перенесенных рабочих дней из джисона
+func (c *HolidayCalendar) loadJSON(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var cf calendarFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return err
+	}
+	for _, list := range []struct {
+		dates []string
+		to    map[string]bool
+	}{{cf.Holidays, c.holidays}, {cf.Workdays, c.workdays}} {
+		for _, d := range list.dates {
+			if _, err := time.Parse(db.TmFormat, d); err != nil {
+				return err
+			}
+			list.to[d] = true
+		}
+	}
+	return nil
+}
+
+// функция загрузки праздников из календаря в формате iCalendar: каждое событие - нерабочие дни
+func (c *HolidayCalendar) loadICS(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// склеиваем перенесенные строки: продолжение начинается с пробела или табуляции
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var inEvent bool
+	var start, end time.Time
+	var rrule string
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// параметры свойства (DTSTART;VALUE=DATE) нам не нужны
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, end, rrule = time.Time{}, time.Time{}, ""
+		case !inEvent:
+			continue
+		case name == "DTSTART" || name == "DTEND":
+			if len(value) < 8 {
+				return fmt.Errorf("wrong %s %q", name, value)
+			}
+			tm, err := time.Parse(db.TmFormat, value[:8])
+			if err != nil {
+				return err
+			}
+			if name == "DTSTART" {
+				start = tm
+			} else {
+				end = tm
+			}
+		case name == "RRULE":
+			rrule = value
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if err := c.addEvent(start, end, rrule); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// функция добавления события календаря: дни с start до end (не включая) нерабочие
+func (c *HolidayCalendar) addEvent(start, end time.Time, rrule string) error {
+	if start.IsZero() {
+		return fmt.Errorf("event without DTSTART")
+	}
+	days := 1
+	if end.After(start) {
+		days = daysBetween(start, end)
+	}
+	if rrule == "" {
+		for i := 0; i < days; i++ {
+			c.holidays[start.AddDate(0, 0, i).Format(db.TmFormat)] = true
+		}
+		return nil
+	}
+	rule, err := Parse(rrule)
+	if err != nil {
+		return err
+	}
+	rule.Start = start
+	// сам календарь для расчета праздников не используем, иначе зациклимся
+	rule.Calendar = Weekends{}
+	c.events = append(c.events, holidayEvent{rule: rule, days: days})
+	return nil
+}
+
+// функция ищет ближайший рабочий день после даты (dir = 1) или перед ней (dir = -1)
+func nextWorkday(cal Calendar, date time.Time, dir int) (time.Time, bool) {
+	for i := 0; i < maxDaysOff; i++ {
+		date = date.AddDate(0, 0, dir)
+		if cal.IsWorkday(date) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
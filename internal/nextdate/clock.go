@@ -0,0 +1,23 @@
+// пакет расчета следующей даты при изменении записи
+package nextdate
+
+import "time"
+
+// источник текущего времени; в тестах подменяется, чтобы результат не зависел от дня запуска
+type Clock interface {
+	Now() time.Time
+}
+
+// системные часы
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// часы, которые всегда показывают одно и то же время
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
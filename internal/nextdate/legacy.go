@@ -0,0 +1,284 @@
+// пакет расчета следующей даты при изменении записи
+package nextdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// максимальный интервал в днях для правила d
+const maxDayInterval = 400
+
+// максимальный интервал в неделях, месяцах или годах для правил w, m, mw, mb и y
+const maxInterval = 100
+
+// максимальный номер рабочего дня месяца для правила mb
+const maxWorkdays = 23
+
+// направления переноса даты с нерабочего дня
+var shiftNames = map[string]int{
+	"next": 1,
+	"prev": -1,
+}
+
+// функция разбора коротких правил: d N, bd N, y, w 1,2,..7, m N,M,.. [O,P,..], mw N:D,.. [O,P,..], mb N,M,.. [O,P,..];
+// после параметров правил w, m, mw, mb и y можно указать интервал /N - каждую N-ю неделю, месяц или год,
+// считая от даты начала серии, а после параметров любого правила - окончание серии until=ГГГГММДД или count=N
+// и перенос даты с нерабочего дня shift=next или shift=prev
+func parseLegacy(repeat string) (Rule, error) {
+	fail := func(t token, msg string) (Rule, error) {
+		return Rule{}, &ParseError{Rule: repeat, Token: t.text, Pos: t.pos, Msg: msg}
+	}
+	rep := token{text: repeat, pos: 1}.split(" ")
+	// недостающий параметр указываем в конце правила
+	missing := token{pos: len(repeat) + 1}
+	r := Rule{form: rep[0].text, interval: 1, wkst: time.Monday}
+	var err error
+
+	// модификаторы пишутся после параметров правила, отделяем их
+	params := rep[:1]
+	seen := make(map[string]bool)
+	for _, t := range rep[1:] {
+		key, val, isKey := strings.Cut(t.text, "=")
+		if !isKey && !strings.HasPrefix(t.text, "/") {
+			if len(seen) > 0 {
+				return fail(t, "parameter after modifier")
+			}
+			params = append(params, t)
+			continue
+		}
+		if !isKey {
+			key, val = "/", t.text[1:]
+		}
+		if seen[key] {
+			return fail(t, "modifier is repeated")
+		}
+		seen[key] = true
+		switch key {
+		case "/":
+			if r.form == "d" || r.form == "bd" {
+				return fail(t, "interval modifier isn't allowed for d and bd rules")
+			}
+			r.interval, err = strconv.Atoi(val)
+			if err != nil || r.interval < 1 || r.interval > maxInterval {
+				return fail(t, fmt.Sprintf("interval must be from 1 to %d", maxInterval))
+			}
+		case "count":
+			r.count, err = strconv.Atoi(val)
+			if err != nil || r.count < 1 {
+				return fail(t, "count must be a positive number")
+			}
+		case "until":
+			r.until, err = time.Parse("20060102", val)
+			if err != nil {
+				return fail(t, "wrong until date")
+			}
+		case "shift":
+			var ok bool
+			if r.shift, ok = shiftNames[val]; !ok {
+				return fail(t, "shift must be next or prev")
+			}
+		default:
+			return fail(t, "unknown modifier")
+		}
+		if seen["count"] && seen["until"] {
+			return fail(t, "count and until can't be used together")
+		}
+	}
+	rep = params
+
+	switch r.form {
+	case "y":
+		if len(rep) > 1 {
+			return fail(rep[1], "unexpected parameter")
+		}
+		r.freq = yearly
+	case "d", "bd":
+		if len(rep) < 2 {
+			return fail(missing, "missing interval in days")
+		}
+		if len(rep) > 2 {
+			return fail(rep[2], "unexpected parameter")
+		}
+		interval, err := strconv.Atoi(rep[1].text)
+		if err != nil || interval < 1 || interval > maxDayInterval {
+			return fail(rep[1], fmt.Sprintf("interval must be from 1 to %d days", maxDayInterval))
+		}
+		r.freq = daily
+		if r.form == "bd" {
+			r.freq = workdaily
+		}
+		r.interval = interval
+	case "w":
+		if len(rep) < 2 {
+			return fail(missing, "missing weekdays")
+		}
+		if len(rep) > 2 {
+			return fail(rep[2], "unexpected parameter")
+		}
+		for _, v := range rep[1].split(",") {
+			weekDayNum, err := strconv.Atoi(v.text)
+			// 0 и 7 - воскресенье
+			if err != nil || weekDayNum > 7 || weekDayNum < 0 {
+				return fail(v, "wrong weekday number")
+			}
+			r.byDay = append(r.byDay, weekdayNum{day: time.Weekday(weekDayNum % 7)})
+		}
+		r.freq = weekly
+	case "m":
+		if len(rep) < 2 {
+			return fail(missing, "missing month days")
+		}
+		if len(rep) > 3 {
+			return fail(rep[3], "unexpected parameter")
+		}
+		for _, v := range rep[1].split(",") {
+			monthDay, err := strconv.Atoi(v.text)
+			// -1 - последний день месяца, -2 - предпоследний
+			if err != nil || monthDay > 31 || monthDay < -2 || monthDay == 0 {
+				return fail(v, "wrong month day")
+			}
+			r.byMonthDay = append(r.byMonthDay, monthDay)
+		}
+		if len(rep) > 2 {
+			if r.byMonth, err = parseLegacyMonths(repeat, rep[2]); err != nil {
+				return Rule{}, err
+			}
+		}
+		r.freq = monthly
+	case "mw":
+		if len(rep) < 2 {
+			return fail(missing, "missing month weekdays")
+		}
+		if len(rep) > 3 {
+			return fail(rep[3], "unexpected parameter")
+		}
+		for _, v := range rep[1].split(",") {
+			// пара "номер:день недели", номер -1 - последний такой день месяца
+			ordStr, dayStr, ok := strings.Cut(v.text, ":")
+			if !ok {
+				return fail(v, "month weekday must look like N:D")
+			}
+			ord, err := strconv.Atoi(ordStr)
+			if err != nil || ord > 5 || ord < -5 || ord == 0 {
+				return fail(token{text: ordStr, pos: v.pos}, "wrong weekday ordinal")
+			}
+			weekDayNum, err := strconv.Atoi(dayStr)
+			if err != nil || weekDayNum > 7 || weekDayNum < 0 {
+				return fail(token{text: dayStr, pos: v.pos + len(ordStr) + 1}, "wrong weekday number")
+			}
+			r.byDay = append(r.byDay, weekdayNum{n: ord, day: time.Weekday(weekDayNum % 7)})
+		}
+		if len(rep) > 2 {
+			if r.byMonth, err = parseLegacyMonths(repeat, rep[2]); err != nil {
+				return Rule{}, err
+			}
+		}
+		r.freq = monthly
+	case "mb":
+		if len(rep) < 2 {
+			return fail(missing, "missing workday numbers")
+		}
+		if len(rep) > 3 {
+			return fail(rep[3], "unexpected parameter")
+		}
+		for _, v := range rep[1].split(",") {
+			// -1 - последний рабочий день месяца
+			workDay, err := strconv.Atoi(v.text)
+			if err != nil || workDay > maxWorkdays || workDay < -maxWorkdays || workDay == 0 {
+				return fail(v, "wrong workday number")
+			}
+			r.byWorkday = append(r.byWorkday, workDay)
+		}
+		if len(rep) > 2 {
+			if r.byMonth, err = parseLegacyMonths(repeat, rep[2]); err != nil {
+				return Rule{}, err
+			}
+		}
+		r.freq = monthly
+	default:
+		return fail(rep[0], "unknown rule type")
+	}
+
+	return r, nil
+}
+
+// функция разбора списка месяцев для правил m, mw и mb
+func parseLegacyMonths(repeat string, t token) ([]int, error) {
+	var months []int
+	for _, v := range t.split(",") {
+		monthNum, err := strconv.Atoi(v.text)
+		if err != nil || monthNum > 12 || monthNum < 1 {
+			return nil, &ParseError{Rule: repeat, Token: v.text, Pos: v.pos, Msg: "wrong month number"}
+		}
+		months = append(months, monthNum)
+	}
+	return months, nil
+}
+
+// функция записи короткого правила
+func (r Rule) legacyString() string {
+	rep := []string{r.form}
+	switch r.form {
+	case "d", "bd":
+		rep = append(rep, strconv.Itoa(r.interval))
+	case "w":
+		days := make([]int, 0, len(r.byDay))
+		for _, wd := range r.byDay {
+			days = append(days, weekdayNumber(wd.day))
+		}
+		rep = append(rep, joinNums(days))
+	case "m":
+		rep = append(rep, joinNums(r.byMonthDay))
+		if len(r.byMonth) > 0 {
+			rep = append(rep, joinNums(r.byMonth))
+		}
+	case "mb":
+		rep = append(rep, joinNums(r.byWorkday))
+		if len(r.byMonth) > 0 {
+			rep = append(rep, joinNums(r.byMonth))
+		}
+	case "mw":
+		days := make([]string, 0, len(r.byDay))
+		for _, wd := range r.byDay {
+			days = append(days, fmt.Sprintf("%d:%d", wd.n, weekdayNumber(wd.day)))
+		}
+		rep = append(rep, strings.Join(days, ","))
+		if len(r.byMonth) > 0 {
+			rep = append(rep, joinNums(r.byMonth))
+		}
+	}
+	if r.interval > 1 && r.form != "d" && r.form != "bd" {
+		rep = append(rep, fmt.Sprintf("/%d", r.interval))
+	}
+	if r.count > 0 {
+		rep = append(rep, fmt.Sprintf("count=%d", r.count))
+	}
+	if !r.until.IsZero() {
+		rep = append(rep, "until="+r.until.Format("20060102"))
+	}
+	if r.shift != 0 {
+		rep = append(rep, "shift="+shiftName(r.shift))
+	}
+	return strings.Join(rep, " ")
+}
+
+// функция возвращает номер дня недели в нашем формате, где воскресенье - 7
+func weekdayNumber(day time.Weekday) int {
+	if day == time.Sunday {
+		return 7
+	}
+	return int(day)
+}
+
+// функция возвращает название направления переноса даты
+func shiftName(shift int) string {
+	for name, v := range shiftNames {
+		if v == shift {
+			return name
+		}
+	}
+	return ""
+}
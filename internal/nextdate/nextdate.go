@@ -3,15 +3,14 @@ package nextdate
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/mrScorpio/finalTask/internal/db"
 )
 
-// функция возвращает новую дату для задачи, принимает (текущее время, дата задачи, правило повторения)
-func NextDate(now time.Time, dstart string, repeat string) (string, error) {
+// функция возвращает новую дату для задачи, принимает (текущее время, дата задачи, правило повторения,
+// даты-исключения, которые нужно пропустить); пустая строка без ошибки означает, что повторять задачу больше не нужно
+func (s Settings) NextDate(now time.Time, dstart string, repeat string, exdates ...string) (string, error) {
 	// если правило повторения пустое, ничего не делаем
 	if repeat == "" {
 		return "", nil
@@ -21,176 +20,122 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	// разбираем строку с правилом повторения в слайс
-	rep := strings.Split(repeat, " ")
-	// если только один символ и это не год, то плохое правило
-	if rep[0] != "y" && len(rep) < 2 {
-		return "", fmt.Errorf("wrong repeat format")
-	}
-	// повторяем год
-	if rep[0] == "y" {
-		for {
-			date = date.AddDate(1, 0, 0)
-			if date.After(now) {
-				break
-			}
-
-		}
-		return date.Format(db.TmFormat), nil
+	// разбираем правило повторения
+	rule, err := Parse(repeat)
+	if err != nil {
+		return "", err
 	}
-	// повторяем дни
-	if rep[0] == "d" {
-		interval, err := strconv.Atoi(rep[1])
+	rule.Start = date
+	rule.Calendar = s.Calendar
+	for _, exdate := range exdates {
+		ex, err := time.Parse(db.TmFormat, exdate)
 		if err != nil {
 			return "", err
 		}
-		if interval > 400 {
-			return "", fmt.Errorf("interval is too big")
-		}
-		for {
-			date = date.AddDate(0, 0, interval)
-			if date.After(now) {
-				break
-			}
-
-		}
-		return date.Format(db.TmFormat), nil
+		rule.Except = append(rule.Except, ex)
 	}
-	// повторяем дни недели
-	if rep[0] == "w" {
-		weekDays := strings.Split(rep[1], ",")
-
-		for {
-			// приращаем дату
-			date = date.AddDate(0, 0, 1)
-			weekDayMatch := false
-			for _, v := range weekDays {
-				weekDayNum, err := strconv.Atoi(v)
-				if err != nil {
-					return "", err
-				}
-				// если левый номер дня
-				if weekDayNum > 7 || weekDayNum < 0 {
-					return "", fmt.Errorf("wrong weekday number")
-				}
-				// воскресенье в буржуйский формат
-				if weekDayNum == 7 {
-					weekDayNum = 0
-				}
-				// если нашли
-				if date.Weekday() == time.Weekday(weekDayNum) {
-					weekDayMatch = true
-					break
-				}
-			}
-			// все совпало - прекращаем поиск
-			if date.After(now) && weekDayMatch {
-				break
-			}
-
-		}
-		return date.Format(db.TmFormat), nil
+	// новая дата должна быть позже и текущего дня, и даты задачи;
+	// текущий день берем в часовом поясе, в котором передано время now
+	after := dateOf(now)
+	if date.After(after) {
+		after = date
 	}
+	next, ok := rule.Next(after)
+	if !ok {
+		return "", nil
+	}
+	return next.Format(db.TmFormat), nil
+}
 
-	// повторяем дни месяца
-	if rep[0] == "m" {
-		monthDays := strings.Split(rep[1], ",")
-		// по-умолчанию месяц правильный
-		monthMatch := true
-		monthNums := make([]string, 0, 12)
-
-		if len(rep) > 2 {
-			monthNums = strings.Split(rep[2], ",")
-			// но если есть задание месяца в правиле, то нужно проверить
-			monthMatch = false
+// функция проверки поля с датой и необходимости ее изменения на момент now
+func (s Settings) CheckDate(task *db.Task, now time.Time) error {
+	// пустой режим повторения заполняется при сохранении задачи
+	switch task.RepeatMode {
+	case "", db.RepeatFixed, db.RepeatAfterCompletion:
+	default:
+		return fmt.Errorf("repeat mode must be %s or %s", db.RepeatFixed, db.RepeatAfterCompletion)
+	}
+	// правило повторения проверяем всегда, чтобы сразу показать, где в нем ошибка
+	if task.Repeat != "" {
+		if _, err := Parse(task.Repeat); err != nil {
+			return err
 		}
-		for {
-			// приращаем дату
-			date = date.AddDate(0, 0, 1)
-			monthDayMatch := false
-			prev := false
-			postPrev := false
-			// цикл для проверки дня
-			for _, v := range monthDays {
-				monthDay, err := strconv.Atoi(v)
-				if err != nil {
-					return "", err
-				}
-				// если левое число
-				if monthDay > 31 || monthDay < -2 || monthDay == 0 {
-					return "", fmt.Errorf("monthday number is bad")
-				}
-				// если предпоследнее число
-				if monthDay == -2 && date.AddDate(0, 0, 2).Day() == 1 {
-					postPrev = true
-				}
-				// если последнее
-				if monthDay == -1 && date.AddDate(0, 0, 1).Day() == 1 {
-					prev = true
-				}
-				// если нашли день
-				if date.Day() == monthDay {
-					monthDayMatch = true
-					break
-				}
-			}
-
-			if !monthMatch {
-				// цикл для проверки месяца
-				for _, v := range monthNums {
-					monthNum, err := strconv.Atoi(v)
-					if err != nil {
-						return "", err
-					}
-					// если левый месяц
-					if monthNum > 12 || monthNum < 1 {
-						return "", fmt.Errorf("month num is wrong")
-					}
-					// если нашли месяц
-					if date.Month() == time.Month(monthNum) && date.Year() == now.Year() {
-						monthMatch = true
-						break
-					}
-				}
-			}
-			// все совпало - прекращаем поиск
-			if date.After(now) && (monthDayMatch || postPrev || prev) && monthMatch {
-				break
-			}
-
+	}
+	if task.DueTime != "" {
+		if _, err := time.Parse(TimeFormat, task.DueTime); err != nil {
+			return fmt.Errorf("wrong time %q", task.DueTime)
 		}
-		return date.Format(db.TmFormat), nil
 	}
-	return "", nil
-}
-
-// функция проверки поля с датой и необходимости ее изменения
-func CheckDate(task *db.Task) error {
-	now := time.Now()
+	// текущий день определяется в часовом поясе задачи
+	now, err := s.TaskNow(task, now)
+	if err != nil {
+		return err
+	}
 	//если дата пустая, кидаем текущую
 	if task.Date == "" {
 		task.Date = now.Format(db.TmFormat)
 		return nil
 	}
-	//преобразуем в тайм
-	t, err := time.Parse(db.TmFormat, task.Date)
-	if err != nil {
+	//проверяем формат
+	if _, err := time.Parse(db.TmFormat, task.Date); err != nil {
 		return err
 	}
-	// если сейчас время больше, чем то, что в задаче, то актуализируем его
-	if now.After(t) && (task.Date != now.Format(db.TmFormat)) {
+	// если дата задачи уже прошла, то актуализируем ее (даты в формате 20060102 можно сравнивать как строки)
+	if task.Date < now.Format(db.TmFormat) {
 		if task.Repeat == "" {
 			// правило повторения пустое - пишем текущую дату
 			task.Date = now.Format(db.TmFormat)
 		} else {
 			// правило есть - используем функцию расчета новой даты
-			next, err := NextDate(now, task.Date, task.Repeat)
+			next, err := s.NextDate(now, task.Date, task.Repeat)
 			if err != nil {
 				return err
 			}
+			// у правила не осталось дат
+			if next == "" {
+				return fmt.Errorf("repeat rule has no dates after now")
+			}
 			task.Date = next
 		}
 	}
 
 	return nil
 }
+
+// функция заполняет количество оставшихся повторений задачи для правила с ограничением по количеству
+func SetRemaining(task *db.Task) error {
+	task.Remaining = 0
+	if task.Repeat == "" {
+		return nil
+	}
+	rule, err := Parse(task.Repeat)
+	if err != nil {
+		return err
+	}
+	task.Remaining = rule.Count()
+	return nil
+}
+
+// функция возвращает до count следующих дат задачи по правилу, не позже until (если until не пустая)
+func (s Settings) Occurrences(now time.Time, dstart string, repeat string, count int, until string) ([]string, error) {
+	if until != "" {
+		if _, err := time.Parse(db.TmFormat, until); err != nil {
+			return nil, err
+		}
+	}
+	dates := make([]string, 0, count)
+	for len(dates) < count {
+		next, err := s.NextDate(now, dstart, repeat)
+		if err != nil {
+			return nil, err
+		}
+		// серия закончилась (даты в формате 20060102 можно сравнивать как строки)
+		if next == "" || until != "" && next > until {
+			break
+		}
+		dates = append(dates, next)
+		// следующую дату ищем после найденной
+		now, _ = time.Parse(db.TmFormat, next)
+	}
+	return dates, nil
+}
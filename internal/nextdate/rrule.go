@@ -0,0 +1,276 @@
+// пакет расчета следующей даты при изменении записи
+package nextdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// названия частот в формате RFC 5545
+var freqNames = map[string]frequency{
+	"DAILY":   daily,
+	"WEEKLY":  weekly,
+	"MONTHLY": monthly,
+	"YEARLY":  yearly,
+}
+
+// двухбуквенные коды дней недели в формате RFC 5545
+var dayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// функция проверяет, записано ли правило в формате RRULE
+func isRRule(repeat string) bool {
+	return strings.Contains(strings.ToUpper(repeat), "FREQ=")
+}
+
+// функция разбора правила вида FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE
+func parseRRule(repeat string) (Rule, error) {
+	r := Rule{interval: 1, wkst: time.Monday}
+	fail := func(t token, msg string) (Rule, error) {
+		return Rule{}, &ParseError{Rule: repeat, Token: t.text, Pos: t.pos, Msg: msg}
+	}
+
+	str := token{text: repeat, pos: 1}
+	if strings.HasPrefix(strings.ToUpper(repeat), "RRULE:") {
+		str = token{text: repeat[len("RRULE:"):], pos: len("RRULE:") + 1}
+	}
+
+	seen := make(map[string]token)
+	var intervalTok token
+	for _, part := range str.split(";") {
+		key, val, ok := strings.Cut(part.text, "=")
+		if !ok || strings.TrimSpace(val) == "" {
+			return fail(part, "rrule part must look like KEY=VALUE")
+		}
+		keyTok := token{text: strings.ToUpper(strings.TrimSpace(key)), pos: part.pos}
+		valTok := token{text: strings.ToUpper(val), pos: part.pos + len(key) + 1}
+		if _, ok := seen[keyTok.text]; ok {
+			return fail(keyTok, "rrule part is repeated")
+		}
+		seen[keyTok.text] = keyTok
+
+		var err *ParseError
+		switch keyTok.text {
+		case "FREQ":
+			freq, ok := freqNames[strings.TrimSpace(valTok.text)]
+			if !ok {
+				return fail(valTok, "unsupported frequency")
+			}
+			r.freq = freq
+		case "INTERVAL":
+			intervalTok = valTok
+			r.interval, err = parsePositive(valTok, "interval must be a positive number")
+		case "COUNT":
+			r.count, err = parsePositive(valTok, "count must be a positive number")
+		case "UNTIL":
+			r.until, err = parseUntil(valTok)
+		case "WKST":
+			day, ok := dayCodes[strings.TrimSpace(valTok.text)]
+			if !ok {
+				return fail(valTok, "wrong week start day")
+			}
+			r.wkst = day
+		case "BYDAY":
+			r.byDay, err = parseByDay(valTok)
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseNums(valTok, -31, 31, "wrong month day")
+		case "BYMONTH":
+			r.byMonth, err = parseNums(valTok, 1, 12, "wrong month number")
+		case "BYSETPOS":
+			r.bySetPos, err = parseNums(valTok, -366, 366, "wrong set position")
+		case "X-SHIFT":
+			// нестандартное расширение: перенос даты с нерабочего дня
+			shift, ok := shiftNames[strings.ToLower(strings.TrimSpace(valTok.text))]
+			if !ok {
+				return fail(valTok, "shift must be NEXT or PREV")
+			}
+			r.shift = shift
+		default:
+			return fail(keyTok, "unsupported rrule part")
+		}
+		if err != nil {
+			err.Rule = repeat
+			return Rule{}, err
+		}
+	}
+
+	end := token{pos: len(repeat) + 1}
+	if _, ok := seen["FREQ"]; !ok {
+		return fail(end, "rrule has no FREQ")
+	}
+	// интервал ограничен так же, как в коротких правилах, иначе Next перебирает слишком много периодов
+	limit := maxInterval
+	if r.freq == daily {
+		limit = maxDayInterval
+	}
+	if r.interval > limit {
+		return fail(intervalTok, fmt.Sprintf("interval must be from 1 to %d", limit))
+	}
+	if _, ok := seen["COUNT"]; ok && !r.until.IsZero() {
+		return fail(later(seen["COUNT"], seen["UNTIL"]), "COUNT and UNTIL can't be used together")
+	}
+	if r.freq == weekly && len(r.byMonthDay) > 0 {
+		return fail(seen["BYMONTHDAY"], "BYMONTHDAY can't be used with weekly frequency")
+	}
+	if r.freq == daily || r.freq == weekly {
+		for _, wd := range r.byDay {
+			if wd.n != 0 {
+				return fail(seen["BYDAY"], "weekday ordinals are allowed only with monthly and yearly frequency")
+			}
+		}
+	}
+	if len(r.bySetPos) > 0 && len(r.byDay) == 0 && len(r.byMonthDay) == 0 && len(r.byMonth) == 0 {
+		return fail(seen["BYSETPOS"], "BYSETPOS needs another BY-part")
+	}
+
+	return r, nil
+}
+
+// функция возвращает фрагмент, стоящий в правиле дальше
+func later(a, b token) token {
+	if a.pos > b.pos {
+		return a
+	}
+	return b
+}
+
+// функция разбора положительного числа
+func parsePositive(t token, msg string) (int, *ParseError) {
+	n, err := strconv.Atoi(strings.TrimSpace(t.text))
+	if err != nil || n < 1 {
+		return 0, &ParseError{Token: t.text, Pos: t.pos, Msg: msg}
+	}
+	return n, nil
+}
+
+// функция разбора даты окончания правила (дата или дата-время)
+func parseUntil(t token) (time.Time, *ParseError) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if tm, err := time.Parse(layout, strings.TrimSpace(t.text)); err == nil {
+			return tm, nil
+		}
+	}
+	return time.Time{}, &ParseError{Token: t.text, Pos: t.pos, Msg: "wrong until date"}
+}
+
+// функция разбора списка дней недели вида MO,2TU,-1FR
+func parseByDay(t token) ([]weekdayNum, *ParseError) {
+	list := t.split(",")
+	res := make([]weekdayNum, 0, len(list))
+	for _, v := range list {
+		if len(v.text) < 2 {
+			return nil, &ParseError{Token: v.text, Pos: v.pos, Msg: "wrong weekday"}
+		}
+		day, ok := dayCodes[v.text[len(v.text)-2:]]
+		if !ok {
+			return nil, &ParseError{Token: v.text, Pos: v.pos, Msg: "wrong weekday"}
+		}
+		wd := weekdayNum{day: day}
+		if ord := v.text[:len(v.text)-2]; ord != "" {
+			n, err := strconv.Atoi(ord)
+			if err != nil || n == 0 || n > 53 || n < -53 {
+				return nil, &ParseError{Token: v.text, Pos: v.pos, Msg: "wrong weekday ordinal"}
+			}
+			wd.n = n
+		}
+		res = append(res, wd)
+	}
+	return res, nil
+}
+
+// функция разбора списка чисел с проверкой диапазона, ноль недопустим
+func parseNums(t token, min, max int, msg string) ([]int, *ParseError) {
+	list := t.split(",")
+	res := make([]int, 0, len(list))
+	for _, v := range list {
+		n, err := strconv.Atoi(v.text)
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, &ParseError{Token: v.text, Pos: v.pos, Msg: msg}
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+// функция записи правила в формате RRULE
+func (r Rule) rruleString() string {
+	parts := []string{"FREQ=" + freqName(r.freq)}
+	if r.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.interval))
+	}
+	if len(r.byDay) > 0 {
+		days := make([]string, 0, len(r.byDay))
+		for _, wd := range r.byDay {
+			day := dayCode(wd.day)
+			if wd.n != 0 {
+				day = strconv.Itoa(wd.n) + day
+			}
+			days = append(days, day)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.byMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinNums(r.byMonthDay))
+	}
+	if len(r.byMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinNums(r.byMonth))
+	}
+	if len(r.bySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinNums(r.bySetPos))
+	}
+	if r.count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.count))
+	}
+	if !r.until.IsZero() {
+		if r.until.Equal(dateOf(r.until)) {
+			parts = append(parts, "UNTIL="+r.until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if r.wkst != time.Monday {
+		parts = append(parts, "WKST="+dayCode(r.wkst))
+	}
+	if r.shift != 0 {
+		parts = append(parts, "X-SHIFT="+strings.ToUpper(shiftName(r.shift)))
+	}
+	return strings.Join(parts, ";")
+}
+
+// функция возвращает название частоты
+func freqName(freq frequency) string {
+	for name, f := range freqNames {
+		if f == freq {
+			return name
+		}
+	}
+	return ""
+}
+
+// функция возвращает двухбуквенный код дня недели
+func dayCode(day time.Weekday) string {
+	for code, d := range dayCodes {
+		if d == day {
+			return code
+		}
+	}
+	return ""
+}
+
+// функция записи списка чисел через запятую
+func joinNums(nums []int) string {
+	list := make([]string, 0, len(nums))
+	for _, n := range nums {
+		list = append(list, strconv.Itoa(n))
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,468 @@
+// пакет расчета следующей даты при изменении записи
+package nextdate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// частота повторения правила
+type frequency int
+
+const (
+	daily frequency = iota
+	weekly
+	monthly
+	yearly
+	// каждый N-й рабочий день
+	workdaily
+)
+
+// максимальное количество просматриваемых периодов, чтобы не зациклиться на невыполнимом правиле
+const maxPeriods = 100000
+
+// день недели с необязательным порядковым номером (2TU - второй вторник, -1FR - последняя пятница)
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+// разобранное правило повторения, в которое приводятся и короткие правила (d, bd, w, m, mw, mb, y), и RRULE
+type Rule struct {
+	// Start - начало серии, от него отсчитываются интервалы и COUNT;
+	// если не задано, серия начинается с даты, переданной в Next
+	Start time.Time
+	// Calendar - календарь рабочих дней; если не задан, выходные - только субботы и воскресенья
+	Calendar Calendar
+	// Except - даты-исключения (EXDATE), которые Next пропускает; в COUNT они учитываются
+	Except []time.Time
+
+	form       string // вид записи: d, bd, w, m, mw, mb, y или пусто для RRULE
+	freq       frequency
+	interval   int
+	byDay      []weekdayNum
+	byMonthDay []int
+	byWorkday  []int // номера рабочих дней месяца, отрицательные - с конца
+	byMonth    []int
+	bySetPos   []int
+	count      int
+	until      time.Time
+	wkst       time.Weekday
+	shift      int // перенос даты с выходного: 1 - на следующий рабочий день, -1 - на предыдущий
+}
+
+// ошибка разбора правила повторения с указанием ошибочного фрагмента
+type ParseError struct {
+	Rule  string // правило целиком
+	Token string // ошибочный фрагмент, пустой - если чего-то не хватает
+	Pos   int    // позиция фрагмента в правиле, считая с единицы
+	Msg   string // описание ошибки
+}
+
+func (e *ParseError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("repeat rule %q: %s at position %d", e.Rule, e.Msg, e.Pos)
+	}
+	return fmt.Sprintf("repeat rule %q: %s: %q at position %d", e.Rule, e.Msg, e.Token, e.Pos)
+}
+
+// фрагмент правила с его позицией
+type token struct {
+	text string
+	pos  int
+}
+
+// функция делит фрагмент по разделителю, запоминая позиции частей; пустые части пропускаются
+func (t token) split(sep string) []token {
+	var res []token
+	pos := t.pos
+	for _, part := range strings.Split(t.text, sep) {
+		if strings.TrimSpace(part) != "" {
+			lead := len(part) - len(strings.TrimLeft(part, " "))
+			res = append(res, token{text: strings.TrimSpace(part), pos: pos + lead})
+		}
+		pos += len(part) + len(sep)
+	}
+	return res
+}
+
+// функция разбора строки с правилом повторения
+func Parse(repeat string) (Rule, error) {
+	if strings.TrimSpace(repeat) == "" {
+		return Rule{}, &ParseError{Rule: repeat, Pos: 1, Msg: "empty rule"}
+	}
+	if isRRule(repeat) {
+		return parseRRule(repeat)
+	}
+	return parseLegacy(repeat)
+}
+
+// функция возвращает правило в том виде, в котором его можно снова разобрать функцией Parse
+func (r Rule) String() string {
+	if r.form != "" {
+		return r.legacyString()
+	}
+	return r.rruleString()
+}
+
+// функция возвращает ограничение серии по количеству повторений, 0 - без ограничения
+func (r Rule) Count() int {
+	return r.count
+}
+
+// функция возвращает последнюю допустимую дату серии, нулевое время - без ограничения
+func (r Rule) Until() time.Time {
+	return r.until
+}
+
+// функция возвращает первую дату серии строго после after;
+// ok == false, если серия закончилась или дат больше нет
+func (r Rule) Next(after time.Time) (time.Time, bool) {
+	start := r.Start
+	if start.IsZero() {
+		start = after
+	}
+	start = dateOf(start)
+	if r.freq == workdaily {
+		return r.nextWorkdays(start, after)
+	}
+	period := r.periodStart(start)
+	// без COUNT не нужно считать даты от начала серии, поэтому сразу перескакиваем поближе к after
+	if r.count == 0 && after.After(start) {
+		period = r.skip(period, dateOf(after))
+	}
+	found := 0
+	for i := 0; i < maxPeriods; i++ {
+		for _, date := range r.expand(period, start) {
+			if date.Before(start) {
+				continue
+			}
+			if !r.until.IsZero() && date.After(r.until) {
+				return time.Time{}, false
+			}
+			found++
+			if r.count > 0 && found > r.count {
+				return time.Time{}, false
+			}
+			if date.After(after) && !r.excluded(date) {
+				return date, true
+			}
+		}
+		period = r.advance(period, r.interval)
+	}
+	return time.Time{}, false
+}
+
+// функция возвращает первую дату серии из каждого N-го рабочего дня, считая от start, строго после after
+func (r Rule) nextWorkdays(start, after time.Time) (time.Time, bool) {
+	cal := r.calendar()
+	date := start
+	// дата начала - первое повторение серии
+	found := 1
+	for i := 0; i < maxPeriods; i++ {
+		for n := 0; n < r.interval; n++ {
+			var ok bool
+			if date, ok = nextWorkday(cal, date, 1); !ok {
+				return time.Time{}, false
+			}
+		}
+		if !r.until.IsZero() && date.After(r.until) {
+			return time.Time{}, false
+		}
+		found++
+		if r.count > 0 && found > r.count {
+			return time.Time{}, false
+		}
+		if date.After(after) && !r.excluded(date) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// функция проверяет, что дата входит в исключения правила
+func (r Rule) excluded(date time.Time) bool {
+	return slices.ContainsFunc(r.Except, func(ex time.Time) bool {
+		return dateOf(ex).Equal(date)
+	})
+}
+
+// функция возвращает календарь рабочих дней правила
+func (r Rule) calendar() Calendar {
+	if r.Calendar != nil {
+		return r.Calendar
+	}
+	return Weekends{}
+}
+
+// функция возвращает начало периода правила, в который попадает дата
+func (r Rule) periodStart(date time.Time) time.Time {
+	switch r.freq {
+	case weekly:
+		shift := (int(date.Weekday()) - int(r.wkst) + 7) % 7
+		return date.AddDate(0, 0, -shift)
+	case monthly:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case yearly:
+		return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+// функция сдвигает начало периода на n периодов
+func (r Rule) advance(period time.Time, n int) time.Time {
+	switch r.freq {
+	case weekly:
+		return period.AddDate(0, 0, 7*n)
+	case monthly:
+		return period.AddDate(0, n, 0)
+	case yearly:
+		return period.AddDate(n, 0, 0)
+	}
+	return period.AddDate(0, 0, n)
+}
+
+// функция пропускает целые интервалы между периодом и датой, оставляя запас в один интервал
+func (r Rule) skip(period, date time.Time) time.Time {
+	var periods int
+	switch r.freq {
+	case daily:
+		periods = daysBetween(period, date)
+	case weekly:
+		periods = daysBetween(period, date) / 7
+	case monthly:
+		periods = (date.Year()-period.Year())*12 + int(date.Month()-period.Month())
+	case yearly:
+		periods = date.Year() - period.Year()
+	}
+	steps := periods/r.interval - 1
+	if steps <= 0 {
+		return period
+	}
+	return r.advance(period, steps*r.interval)
+}
+
+// функция возвращает отсортированный список дат правила внутри периода
+func (r Rule) expand(period, start time.Time) []time.Time {
+	var dates []time.Time
+	switch r.freq {
+	case daily:
+		if r.matchMonth(period) && r.matchMonthDay(period) && r.matchWeekday(period) {
+			dates = append(dates, period)
+		}
+	case weekly:
+		for i := 0; i < 7; i++ {
+			date := period.AddDate(0, 0, i)
+			if !r.matchMonth(date) {
+				continue
+			}
+			if len(r.byDay) > 0 && r.matchWeekday(date) || len(r.byDay) == 0 && date.Weekday() == start.Weekday() {
+				dates = append(dates, date)
+			}
+		}
+	case monthly:
+		if r.matchMonth(period) {
+			dates = r.expandMonth(period, start)
+		}
+	case yearly:
+		dates = r.expandYear(period, start)
+	}
+	return r.applyShift(r.applySetPos(dates))
+}
+
+// функция переносит даты, выпавшие на нерабочие дни, на ближайший рабочий день
+func (r Rule) applyShift(dates []time.Time) []time.Time {
+	if r.shift == 0 {
+		return dates
+	}
+	cal := r.calendar()
+	res := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		if !cal.IsWorkday(date) {
+			var ok bool
+			if date, ok = nextWorkday(cal, date, r.shift); !ok {
+				continue
+			}
+		}
+		res = append(res, date)
+	}
+	return sortDates(res)
+}
+
+// функция раскрывает правило внутри одного месяца
+func (r Rule) expandMonth(month, start time.Time) []time.Time {
+	last := month.AddDate(0, 1, -1)
+	var dates []time.Time
+	switch {
+	case len(r.byWorkday) > 0:
+		var workdays []time.Time
+		cal := r.calendar()
+		for date := month; !date.After(last); date = date.AddDate(0, 0, 1) {
+			if cal.IsWorkday(date) {
+				workdays = append(workdays, date)
+			}
+		}
+		for _, n := range r.byWorkday {
+			idx := n - 1
+			if n < 0 {
+				idx = len(workdays) + n
+			}
+			if idx >= 0 && idx < len(workdays) {
+				dates = append(dates, workdays[idx])
+			}
+		}
+	case len(r.byMonthDay) > 0:
+		var allowed []time.Time
+		if len(r.byDay) > 0 {
+			allowed = expandByDay(r.byDay, month, last)
+		}
+		for _, md := range r.byMonthDay {
+			date, ok := monthDay(month, md)
+			if !ok {
+				continue
+			}
+			if len(r.byDay) > 0 && !slices.ContainsFunc(allowed, date.Equal) {
+				continue
+			}
+			dates = append(dates, date)
+		}
+	case len(r.byDay) > 0:
+		dates = expandByDay(r.byDay, month, last)
+	default:
+		if date, ok := monthDay(month, start.Day()); ok {
+			dates = append(dates, date)
+		}
+	}
+	return sortDates(dates)
+}
+
+// функция раскрывает правило внутри одного года
+func (r Rule) expandYear(year, start time.Time) []time.Time {
+	var dates []time.Time
+	switch {
+	case len(r.byMonth) > 0 || len(r.byMonthDay) > 0:
+		for m := time.January; m <= time.December; m++ {
+			month := time.Date(year.Year(), m, 1, 0, 0, 0, 0, time.UTC)
+			if r.matchMonth(month) {
+				dates = append(dates, r.expandMonth(month, start)...)
+			}
+		}
+	case len(r.byDay) > 0:
+		dates = expandByDay(r.byDay, year, year.AddDate(1, 0, -1))
+	default:
+		date := time.Date(year.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		// 29 февраля бывает не каждый год: RRULE такой год пропускает,
+		// а короткое правило y переносит дату на 1 марта
+		if date.Day() == start.Day() || r.form == "y" {
+			dates = append(dates, date)
+		}
+	}
+	return sortDates(dates)
+}
+
+// функция применяет BYSETPOS к датам периода
+func (r Rule) applySetPos(dates []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 || len(dates) == 0 {
+		return dates
+	}
+	res := make([]time.Time, 0, len(r.bySetPos))
+	for _, pos := range r.bySetPos {
+		idx := pos - 1
+		if pos < 0 {
+			idx = len(dates) + pos
+		}
+		if idx >= 0 && idx < len(dates) {
+			res = append(res, dates[idx])
+		}
+	}
+	return sortDates(res)
+}
+
+// функция проверяет месяц даты по BYMONTH
+func (r Rule) matchMonth(date time.Time) bool {
+	return len(r.byMonth) == 0 || slices.Contains(r.byMonth, int(date.Month()))
+}
+
+// функция проверяет число месяца по BYMONTHDAY
+func (r Rule) matchMonthDay(date time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+	month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, md := range r.byMonthDay {
+		if d, ok := monthDay(month, md); ok && d.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// функция проверяет день недели по BYDAY без порядковых номеров
+func (r Rule) matchWeekday(date time.Time) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	for _, wd := range r.byDay {
+		if wd.day == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// функция раскрывает дни недели с порядковыми номерами в диапазоне дат [from, to]
+func expandByDay(byDay []weekdayNum, from, to time.Time) []time.Time {
+	var dates []time.Time
+	for _, wd := range byDay {
+		// первый и последний такой день недели в диапазоне
+		first := from.AddDate(0, 0, (int(wd.day)-int(from.Weekday())+7)%7)
+		last := to.AddDate(0, 0, -((int(to.Weekday()) - int(wd.day) + 7) % 7))
+		switch {
+		case wd.n > 0:
+			date := first.AddDate(0, 0, 7*(wd.n-1))
+			if !date.After(to) {
+				dates = append(dates, date)
+			}
+		case wd.n < 0:
+			date := last.AddDate(0, 0, 7*(wd.n+1))
+			if !date.Before(from) {
+				dates = append(dates, date)
+			}
+		default:
+			for date := first; !date.After(to); date = date.AddDate(0, 0, 7) {
+				dates = append(dates, date)
+			}
+		}
+	}
+	return dates
+}
+
+// функция возвращает число месяца (отрицательное - с конца месяца), ok == false, если такого числа нет
+func monthDay(month time.Time, day int) (time.Time, bool) {
+	last := month.AddDate(0, 1, -1).Day()
+	if day < 0 {
+		day = last + day + 1
+	}
+	if day < 1 || day > last {
+		return time.Time{}, false
+	}
+	return time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// функция сортирует даты и убирает повторы
+func sortDates(dates []time.Time) []time.Time {
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(dates, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+// функция отбрасывает время, оставляя дату в UTC
+func dateOf(tm time.Time) time.Time {
+	return time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// функция возвращает количество дней между датами
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,87 @@
+// пакет расчета следующей даты при изменении записи
+package nextdate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// формат времени дня у задачи
+const TimeFormat = "15:04"
+
+// настройки расчета дат, общие для задач одного сервера; нулевое значение - пояс сервера
+// и календарь, в котором выходные только субботы и воскресенья
+type Settings struct {
+	Location *time.Location // часовой пояс для задач, у которых не указан свой
+	Calendar Calendar       // календарь рабочих дней для правил bd, mb и shift
+}
+
+// функция загрузки настроек: часового пояса по имени (пустое - пояс сервера) и календарей праздников из файлов
+func LoadSettings(timeZone string, holidays []string) (Settings, error) {
+	var s Settings
+	if timeZone != "" {
+		loc, err := s.LoadLocation(timeZone)
+		if err != nil {
+			return Settings{}, err
+		}
+		s.Location = loc
+	}
+	if len(holidays) > 0 {
+		cal, err := LoadCalendar(holidays...)
+		if err != nil {
+			return Settings{}, err
+		}
+		s.Calendar = cal
+	}
+	return s, nil
+}
+
+// функция возвращает часовой пояс по имени из базы IANA, для пустого имени - пояс по умолчанию
+func (s Settings) LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		if s.Location == nil {
+			return time.Local, nil
+		}
+		return s.Location, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// функция переводит текущее время в часовой пояс задачи
+func (s Settings) TaskNow(task *db.Task, now time.Time) (time.Time, error) {
+	loc, err := s.LoadLocation(task.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.In(loc), nil
+}
+
+// функция заполняет срок выполнения задачи по дате, времени и поясу; при переходе на летнее время
+// несуществующее время дня сдвигается вперед, а у повторяющейся задачи остается прежним в следующих датах
+func (s Settings) SetDue(task *db.Task) error {
+	task.Due = ""
+	if task.DueTime == "" || task.Date == "" {
+		return nil
+	}
+	loc, err := s.LoadLocation(task.TimeZone)
+	if err != nil {
+		return err
+	}
+	date, err := time.Parse(db.TmFormat, task.Date)
+	if err != nil {
+		return err
+	}
+	tm, err := time.Parse(TimeFormat, task.DueTime)
+	if err != nil {
+		return fmt.Errorf("wrong time %q", task.DueTime)
+	}
+	due := time.Date(date.Year(), date.Month(), date.Day(), tm.Hour(), tm.Minute(), 0, 0, loc)
+	task.Due = due.Format(time.RFC3339)
+	return nil
+}
@@ -11,31 +11,81 @@ import (
 
 // структура сервера с прикрученным логом
 type MyServ struct {
-	Serv  http.Server
-	Loger log.Logger
+	Serv  *http.Server
+	Loger *log.Logger
 }
 
-// функция создания нового экзепляра сервера с логом
-func NewServer(loger log.Logger, port string) *MyServ {
+// функция создания нового экзепляра сервера с логом, маршруты берутся из хэндлеров h
+func NewServer(h *handlers.Handlers, loger *log.Logger, port string) *MyServ {
 	mux := http.NewServeMux()
 
 	mux.Handle("/", http.FileServer(http.Dir("./web")))
-	mux.HandleFunc("/api/nextdate", handlers.NextDateHandler)
-	mux.HandleFunc("/api/task", handlers.Auth(handlers.TaskHandler))
-	mux.HandleFunc("/api/tasks", handlers.Auth(handlers.TasksHandler))
-	mux.HandleFunc("/api/task/done", handlers.Auth(handlers.TaskDoneHandler))
-	mux.HandleFunc("/api/signin", handlers.ChkPass)
+	mux.HandleFunc("/api/nextdate", h.NextDateHandler)
+	mux.HandleFunc("/api/occurrences", h.OccurrencesHandler)
+	mux.HandleFunc("/api/task", h.Auth(h.TaskHandler))
+	mux.HandleFunc("/api/tasks", h.Auth(h.TasksHandler))
+	mux.HandleFunc("/api/task/done", h.Auth(h.TaskDoneHandler))
+	mux.HandleFunc("/api/task/skip", h.Auth(h.TaskSkipHandler))
+	mux.HandleFunc("/api/task/reschedule", h.Auth(h.TaskRescheduleHandler))
+	mux.HandleFunc("/api/task/move", h.Auth(h.TaskMoveHandler))
+	mux.HandleFunc("/api/task/status", h.Auth(h.TaskStatusHandler))
+	mux.HandleFunc("/api/task/checklist", h.Auth(h.TaskChecklistHandler))
+	mux.HandleFunc("/api/task/dependency", h.Auth(h.TaskDependencyHandler))
+	mux.HandleFunc("/api/task/graph", h.Auth(h.TaskGraphHandler))
+	mux.HandleFunc("/api/board", h.Auth(h.BoardHandler))
+	mux.HandleFunc("/api/history", h.Auth(h.HistoryHandler))
+	mux.HandleFunc("/api/history/undo", h.Auth(h.HistoryUndoHandler))
+	mux.HandleFunc("/api/trash", h.Auth(h.TrashHandler))
+	mux.HandleFunc("/api/trash/restore", h.Auth(h.TrashRestoreHandler))
+	mux.HandleFunc("/api/tags", h.Auth(h.TagsHandler))
+	mux.HandleFunc("/api/lists", h.Auth(h.ListsHandler))
+	mux.HandleFunc("/api/list", h.Auth(h.ListHandler))
+	mux.HandleFunc("/api/signin", h.ChkPass)
+	mux.HandleFunc("/api/openapi.json", h.OpenAPIHandler)
 
-	serv := http.Server{
+	// API v2 в отдельном муксе: на неподдерживаемый метод он сам отвечает 405
+	v2 := http.NewServeMux()
+	v2.HandleFunc("GET /api/v2/tasks", h.AuthV2(h.ListTasksV2))
+	v2.HandleFunc("POST /api/v2/tasks", h.AuthV2(h.CreateTaskV2))
+	v2.HandleFunc("GET /api/v2/tasks/{id}", h.AuthV2(h.GetTaskV2))
+	v2.HandleFunc("PUT /api/v2/tasks/{id}", h.AuthV2(h.UpdateTaskV2))
+	v2.HandleFunc("DELETE /api/v2/tasks/{id}", h.AuthV2(h.DeleteTaskV2))
+	v2.HandleFunc("POST /api/v2/tasks/{id}/complete", h.AuthV2(h.CompleteTaskV2))
+	v2.HandleFunc("POST /api/v2/tasks/{id}/skip", h.AuthV2(h.SkipTaskV2))
+	v2.HandleFunc("POST /api/v2/tasks/{id}/reschedule", h.AuthV2(h.RescheduleTaskV2))
+	v2.HandleFunc("POST /api/v2/tasks/{id}/move", h.AuthV2(h.MoveTaskV2))
+	v2.HandleFunc("POST /api/v2/tasks/{id}/status", h.AuthV2(h.SetStatusV2))
+	v2.HandleFunc("GET /api/v2/tasks/{id}/checklist", h.AuthV2(h.ListChecklistV2))
+	v2.HandleFunc("POST /api/v2/tasks/{id}/checklist", h.AuthV2(h.CreateItemV2))
+	v2.HandleFunc("PUT /api/v2/tasks/{id}/checklist/{item}", h.AuthV2(h.UpdateItemV2))
+	v2.HandleFunc("DELETE /api/v2/tasks/{id}/checklist/{item}", h.AuthV2(h.DeleteItemV2))
+	v2.HandleFunc("POST /api/v2/tasks/{id}/dependencies", h.AuthV2(h.AddDependencyV2))
+	v2.HandleFunc("DELETE /api/v2/tasks/{id}/dependencies/{dep}", h.AuthV2(h.DeleteDependencyV2))
+	v2.HandleFunc("GET /api/v2/tasks/{id}/graph", h.AuthV2(h.TaskGraphV2))
+	v2.HandleFunc("GET /api/v2/board", h.AuthV2(h.BoardV2))
+	v2.HandleFunc("GET /api/v2/history", h.AuthV2(h.HistoryV2))
+	v2.HandleFunc("POST /api/v2/history/undo", h.AuthV2(h.UndoCompletionV2))
+	v2.HandleFunc("GET /api/v2/trash", h.AuthV2(h.ListTrashV2))
+	v2.HandleFunc("POST /api/v2/trash/{id}/restore", h.AuthV2(h.RestoreTaskV2))
+	v2.HandleFunc("GET /api/v2/tags", h.AuthV2(h.ListTagsV2))
+	v2.HandleFunc("PUT /api/v2/tags/{name}", h.AuthV2(h.RenameTagV2))
+	v2.HandleFunc("POST /api/v2/tags/{name}/merge", h.AuthV2(h.MergeTagV2))
+	v2.HandleFunc("DELETE /api/v2/tags/{name}", h.AuthV2(h.DeleteTagV2))
+	v2.HandleFunc("GET /api/v2/lists", h.AuthV2(h.ListListsV2))
+	v2.HandleFunc("POST /api/v2/lists", h.AuthV2(h.CreateListV2))
+	v2.HandleFunc("GET /api/v2/lists/{id}", h.AuthV2(h.GetListV2))
+	v2.HandleFunc("PUT /api/v2/lists/{id}", h.AuthV2(h.UpdateListV2))
+	v2.HandleFunc("DELETE /api/v2/lists/{id}", h.AuthV2(h.DeleteListV2))
+	mux.Handle("/api/v2/", v2)
+
+	serv := &http.Server{
 		Addr:         ":" + port,
 		Handler:      mux,
-		ErrorLog:     &loger,
+		ErrorLog:     loger,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  15 * time.Second,
 	}
 
-	myserv := MyServ{Loger: loger, Serv: serv}
-
-	return &myserv
+	return &MyServ{Loger: loger, Serv: serv}
 }
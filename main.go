@@ -4,13 +4,29 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
+	_ "time/tzdata"
 
+	"github.com/mrScorpio/finalTask/internal/config"
 	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/mrScorpio/finalTask/internal/handlers"
+	"github.com/mrScorpio/finalTask/internal/nextdate"
 	"github.com/mrScorpio/finalTask/internal/server"
 	"github.com/mrScorpio/finalTask/tests"
 )
 
 func main() {
+	cfg := config.FromEnv()
+
+	// подкоманда управления схемой БД: todoapp migrate status|up|rollback [N]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.DSN, cfg.DBFile, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	logFile, err := os.OpenFile(`server.log`, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Fatal(fmt.Errorf("can't open log-file: %w", err))
@@ -19,24 +35,35 @@ func main() {
 
 	myLog := log.New(logFile, `http-server`, log.LstdFlags|log.Lshortfile)
 
-	port := os.Getenv("TODO_PORT")
-	if port == "" {
-		port = fmt.Sprint(tests.Port)
+	if cfg.Port == "" {
+		cfg.Port = fmt.Sprint(tests.Port)
 	}
 
-	myServ := server.NewServer(*myLog, port)
-
-	dbFile := os.Getenv("TODO_DBFILE")
+	// часовой пояс пользователя для задач без своего пояса и календари праздников
+	if err := cfg.LoadDates(); err != nil {
+		myLog.Fatal(err.Error())
+	}
 
-	if dbFile == "" {
-		dbFile = "scheduler.db"
+	// срок хранения задач в корзине
+	retention, err := config.ParseRetention(cfg.TrashRetention)
+	if err != nil {
+		myLog.Fatal(err.Error())
 	}
 
-	err = db.Init(dbFile)
+	// хранилище задач: по умолчанию SQLite в файле TODO_DBFILE
+	store, err := db.NewStore(cfg.DSN, cfg.DBFile)
 	if err != nil {
 		myLog.Fatal(err.Error())
 	}
-	defer db.CloseDb()
+	defer store.Close()
+
+	h := handlers.New(store, cfg, nextdate.SystemClock{}, myLog)
+	// задачи, которые пролежали в корзине дольше срока хранения, удаляются окончательно раз в час
+	stop := make(chan struct{})
+	defer close(stop)
+	go h.RunPurger(retention, time.Hour, stop)
+
+	myServ := server.NewServer(h, myLog, cfg.Port)
 
 	err = myServ.Serv.ListenAndServe()
 	if err != nil {
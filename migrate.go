@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/mrScorpio/finalTask/internal/db"
+)
+
+// функция выполнения подкоманды migrate: status - список шагов схемы, up - применить новые,
+// rollback [N] - откатить N последних шагов (по умолчанию один)
+func runMigrate(dsn, dbFile string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate status|up|rollback [N]")
+	}
+	store, err := db.OpenStore(dsn, dbFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	m, ok := store.(db.Migrator)
+	if !ok {
+		return fmt.Errorf("this database has no schema to migrate")
+	}
+
+	switch args[0] {
+	case "status":
+		if len(args) > 1 {
+			return fmt.Errorf("usage: migrate status")
+		}
+	case "up":
+		if len(args) > 1 {
+			return fmt.Errorf("usage: migrate up")
+		}
+		if err := m.Migrate(); err != nil {
+			return err
+		}
+	case "rollback":
+		steps := 1
+		if len(args) > 2 {
+			return fmt.Errorf("usage: migrate rollback [N]")
+		}
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("number of steps must be a positive number")
+			}
+		}
+		if err := m.Rollback(steps); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	return printMigrations(m)
+}
+
+// функция вывода состояния шагов схемы в виде таблицы
+func printMigrations(m db.Migrator) error {
+	states, err := m.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, st := range states {
+		at := "pending"
+		if st.Applied {
+			at = st.AppliedAt
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, at)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// проверки приоритета и статуса, общие для всех хранилищ
+func checkWorkflow(t *testing.T, store db.TaskStore) {
+	ids := make(map[string]int)
+	for _, task := range []db.Task{
+		{Date: "20240127", Title: "Отчет", Priority: db.PriorityLow},
+		{Date: "20240126", Title: "Почта"},
+		{Date: "20240126", Title: "Релиз", Priority: db.PriorityUrgent, Status: db.StatusInProgress, Estimate: 90},
+		{Date: "20240126", Title: "Созвон", Priority: db.PriorityHigh, DueTime: "15:00"},
+		{Date: "20240126", Title: "Обед", Priority: db.PriorityHigh, DueTime: "13:00"},
+	} {
+		id, err := store.AddTask(&task)
+		assert.NoError(t, err)
+		ids[task.Title] = int(id)
+	}
+
+	// пустые приоритет и статус заменяются обычными
+	task, err := store.GetTask(strconv.Itoa(ids["Почта"]))
+	if assert.NoError(t, err) {
+		assert.Equal(t, db.PriorityNormal, task.Priority)
+		assert.Equal(t, db.StatusTodo, task.Status)
+	}
+	task, err = store.GetTask(strconv.Itoa(ids["Релиз"]))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 90, task.Estimate)
+	}
+
+	// в пределах даты сначала срочные, при равном приоритете - по времени
+	order := []string{"Релиз", "Обед", "Созвон", "Почта", "Отчет"}
+	assert.Equal(t, order, tagTitles(t, store, db.TaskQuery{}))
+	// курсор листает с учетом приоритета
+	var titles []string
+	q := db.TaskQuery{Limit: 2}
+	for {
+		page, err := store.ListTasks(q)
+		if !assert.NoError(t, err) {
+			return
+		}
+		titles = append(titles, graphTitles(page.Tasks)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	assert.Equal(t, order, titles)
+
+	assert.Equal(t, []string{"Релиз"}, tagTitles(t, store, db.TaskQuery{Status: db.StatusInProgress}))
+	assert.NoError(t, store.SetStatus(strconv.Itoa(ids["Почта"]), db.StatusDone))
+	assert.Equal(t, []string{"Почта"}, tagTitles(t, store, db.TaskQuery{Status: db.StatusDone}))
+	assert.ErrorIs(t, store.SetStatus("999999", db.StatusDone), db.ErrNotFound)
+
+	// изменение задачи меняет и приоритет
+	task, err = store.GetTask(strconv.Itoa(ids["Отчет"]))
+	if assert.NoError(t, err) {
+		task.Date, task.Priority = "20240126", db.PriorityUrgent
+		assert.NoError(t, store.UpdTask(task))
+	}
+	assert.Equal(t, []string{"Отчет", "Релиз", "Обед", "Созвон", "Почта"}, tagTitles(t, store, db.TaskQuery{}))
+}
+
+func TestWorkflowStore(t *testing.T) {
+	eachStore(t, checkWorkflow)
+}
+
+// функция возвращает заголовки задач колонок доски по статусам
+func boardTitles(ret map[string]any) map[string][]string {
+	board := make(map[string][]string)
+	columns, _ := ret["columns"].([]any)
+	for _, c := range columns {
+		column := c.(map[string]any)
+		status, _ := column["status"].(string)
+		tasks, _ := column["tasks"].([]any)
+		titles := []string{}
+		for _, task := range tasks {
+			title, _ := task.(map[string]any)["title"].(string)
+			titles = append(titles, title)
+		}
+		board[status] = titles
+	}
+	return board
+}
+
+func TestBoardAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	code, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Релиз",
+		"priority": "urgent", "status": "in_progress", "estimate": "90"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "urgent", ret["priority"])
+	assert.Equal(t, "90", ret["estimate"])
+	first, _ := ret["id"].(string)
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Зарядка", "repeat": "d 1"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "normal", ret["priority"])
+	assert.Equal(t, "todo", ret["status"])
+	second, _ := ret["id"].(string)
+
+	for _, v := range []struct {
+		body  map[string]any
+		field string
+	}{
+		{map[string]any{"title": "Релиз", "priority": "важно"}, "priority"},
+		{map[string]any{"title": "Релиз", "status": "later"}, "status"},
+		{map[string]any{"title": "Релиз", "estimate": "-5"}, "estimate"},
+	} {
+		code, ret = callJSON(t, srv, http.MethodPost, "/api/task", v.body)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.NotEmpty(t, ret["error"])
+		data, _ := json.Marshal(v.body)
+		resp, ret := callV2(t, srv, http.MethodPost, "/api/v2/tasks", string(data))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, map[string]any{"field": v.field}, ret["details"])
+	}
+
+	// изменение без полей приоритета, статуса и оценки их не меняет
+	code, _ = callJSON(t, srv, http.MethodPut, "/api/task", map[string]any{"id": first, "date": "20240126", "title": "Релиз 2.0"})
+	assert.Equal(t, http.StatusOK, code)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+first, nil)
+	assert.Equal(t, "urgent", ret["priority"])
+	assert.Equal(t, "in_progress", ret["status"])
+	assert.Equal(t, "90", ret["estimate"])
+
+	code, ret = callJSON(t, srv, http.MethodGet, "/api/board", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string][]string{"todo": {"Зарядка"}, "in_progress": {"Релиз 2.0"}, "waiting": {}, "done": {}},
+		boardTitles(ret))
+	code, ret = callJSON(t, srv, http.MethodGet, "/api/board?cursor=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "cursor needs status", ret["error"])
+
+	// перенос по доске и выполнение повторяющейся задачи, после которого она снова в todo
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/status?id="+second+"&status=done", nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task/status?id="+second+"&status=later", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "status must be todo, in_progress, waiting or done", ret["error"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?status=done", nil)
+	assert.Equal(t, float64(1), ret["total"])
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/done?id="+second, nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+second, nil)
+	assert.Equal(t, "20240127", ret["date"])
+	assert.Equal(t, "todo", ret["status"])
+
+	resp, ret := callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+first+"/status", `{"status": "waiting"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "waiting", ret["status"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+first+"/status", `{"status": ""}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]any{"field": "status"}, ret["details"])
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/tasks/999999/status", `{"status": "done"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/board?status=waiting", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string][]string{"waiting": {"Релиз 2.0"}}, boardTitles(ret))
+	resp, _ = callV2(t, srv, http.MethodGet, "/api/v2/board?status=later", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestBoard(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	ret, err := postJSON("api/task", map[string]any{"date": today, "title": "Релиз", "priority": "high", "estimate": "30"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+
+	ret, err = postJSON("api/task/status?id="+id+"&status=in_progress", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, "high", task.Priority)
+	assert.Equal(t, "in_progress", task.Status)
+	assert.Equal(t, int64(30), task.Estimate)
+
+	ret, err = postJSON("api/board?status=in_progress", nil, http.MethodGet)
+	assert.NoError(t, err)
+	if columns, _ := ret["columns"].([]any); assert.Len(t, columns, 1) {
+		assert.Equal(t, float64(1), columns[0].(map[string]any)["total"])
+	}
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
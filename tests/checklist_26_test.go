@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// функция возвращает тексты пунктов чек-листа задачи по порядку
+func itemTexts(t *testing.T, store db.TaskStore, taskId int) []string {
+	items, err := store.ChecklistItems(taskId)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	var texts []string
+	for _, item := range items {
+		texts = append(texts, item.Text)
+	}
+	return texts
+}
+
+// проверки чек-листов, общие для всех хранилищ
+func checkChecklist(t *testing.T, store db.TaskStore) {
+	id, err := store.AddTask(&db.Task{Date: "20240126", Title: "Уборка", Repeat: "d 7"})
+	assert.NoError(t, err)
+	taskId := int(id)
+	other, err := store.AddTask(&db.Task{Date: "20240126", Title: "Отчет"})
+	assert.NoError(t, err)
+
+	// без пунктов прогресса нет
+	task, err := store.GetTask(strconv.Itoa(taskId))
+	if assert.NoError(t, err) {
+		assert.Nil(t, task.Progress)
+	}
+	items, err := store.ChecklistItems(taskId)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	ids := make(map[string]int)
+	for _, v := range []struct {
+		text, parent string
+		done         bool
+	}{
+		{"Пропылесосить", "", true},
+		{"Помыть пол", "", false},
+		{"Ковер", "Пропылесосить", true},
+		{"Диван", "Пропылесосить", false},
+	} {
+		itemId, err := store.AddChecklistItem(&db.ChecklistItem{TaskId: taskId, ParentId: ids[v.parent], Text: v.text, Done: v.done})
+		assert.NoError(t, err)
+		ids[v.text] = int(itemId)
+	}
+	_, err = store.AddChecklistItem(&db.ChecklistItem{TaskId: 999999, Text: "Тест"})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = store.AddChecklistItem(&db.ChecklistItem{TaskId: int(other), ParentId: ids["Ковер"], Text: "Тест"})
+	assert.ErrorIs(t, err, db.ErrItemNotFound)
+
+	items, err = store.ChecklistItems(taskId)
+	if assert.NoError(t, err) && assert.Len(t, items, 4) {
+		assert.Equal(t, &db.ChecklistItem{Id: ids["Диван"], TaskId: taskId, ParentId: ids["Пропылесосить"], Text: "Диван", Position: 2}, items[3])
+	}
+	assert.Equal(t, []string{"Пропылесосить", "Помыть пол", "Ковер", "Диван"}, itemTexts(t, store, taskId))
+	task, err = store.GetTask(strconv.Itoa(taskId))
+	if assert.NoError(t, err) {
+		assert.Equal(t, &db.Progress{Done: 2, Total: 4}, task.Progress)
+	}
+	assert.Equal(t, []string{"Уборка"}, tagTitles(t, store, db.TaskQuery{Search: "уборка"}))
+	page, err := store.ListTasks(db.TaskQuery{Limit: 10, Sort: db.SortTitle})
+	if assert.NoError(t, err) && assert.Len(t, page.Tasks, 2) {
+		assert.Nil(t, page.Tasks[0].Progress)
+		assert.Equal(t, &db.Progress{Done: 2, Total: 4}, page.Tasks[1].Progress)
+	}
+
+	// изменение пункта и порядок по месту
+	assert.NoError(t, store.UpdChecklistItem(&db.ChecklistItem{Id: ids["Помыть пол"], TaskId: taskId, Text: "Помыть пол", Done: true}))
+	assert.ErrorIs(t, store.UpdChecklistItem(&db.ChecklistItem{Id: ids["Помыть пол"], TaskId: int(other), Text: "Тест"}), db.ErrItemNotFound)
+	assert.Equal(t, []string{"Помыть пол", "Пропылесосить", "Ковер", "Диван"}, itemTexts(t, store, taskId))
+
+	// переход на следующее повторение снимает отметки
+	assert.NoError(t, store.AdvanceTask(&db.Advance{TaskId: taskId, Date: "20240202"}))
+	task, err = store.GetTask(strconv.Itoa(taskId))
+	if assert.NoError(t, err) {
+		assert.Equal(t, &db.Progress{Done: 0, Total: 4}, task.Progress)
+	}
+
+	// пункт удаляется вместе с вложенными, а чек-лист - вместе с задачей
+	assert.ErrorIs(t, store.DelChecklistItem(int(other), ids["Пропылесосить"]), db.ErrItemNotFound)
+	assert.NoError(t, store.DelChecklistItem(taskId, ids["Пропылесосить"]))
+	assert.ErrorIs(t, store.DelChecklistItem(taskId, ids["Ковер"]), db.ErrItemNotFound)
+	assert.Equal(t, []string{"Помыть пол"}, itemTexts(t, store, taskId))
+	assert.NoError(t, store.DelTask(strconv.Itoa(taskId)))
+	items, err = store.ChecklistItems(taskId)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestChecklistStore(t *testing.T) {
+	eachStore(t, checkChecklist)
+}
+
+func TestChecklistAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	code, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Уборка", "repeat": "d 7"})
+	assert.Equal(t, http.StatusOK, code)
+	id, _ := ret["id"].(string)
+	assert.Nil(t, ret["progress"])
+
+	// пункты добавляются после пунктов того же уровня
+	resp, ret := callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/checklist", `{"text": " Пропылесосить "}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, map[string]any{"id": "1", "task_id": id, "text": "Пропылесосить", "done": false, "position": float64(1)}, ret)
+	assert.Equal(t, "/api/v2/tasks/"+id+"/checklist/1", resp.Header.Get("Location"))
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task/checklist?id="+id, map[string]any{"text": "Ковер", "parent_id": "1"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1", ret["parent_id"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/checklist", `{"text": "Тест", "parent_id": "9"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]any{"field": "parent_id"}, ret["details"])
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task/checklist?id="+id, map[string]any{"text": ""})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, ret["error"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task/checklist?id="+id, nil)
+	assert.Len(t, ret["items"], 2)
+
+	// без поля text пункт сохраняет прежний текст
+	resp, ret = callV2(t, srv, http.MethodPut, "/api/v2/tasks/"+id+"/checklist/1", `{"done": true}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Пропылесосить", ret["text"])
+	assert.Equal(t, true, ret["done"])
+	resp, _ = callV2(t, srv, http.MethodPut, "/api/v2/tasks/"+id+"/checklist/3", `{"done": true}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, map[string]any{"done": float64(1), "total": float64(2)}, ret["progress"])
+
+	// пока есть неотмеченные пункты, задача выполняется только с force
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "conflict", ret["code"])
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "task has open checklist items", ret["error"])
+	code, _ = callJSON(t, srv, http.MethodPut, "/api/task/checklist?id="+id, map[string]any{"id": "2", "done": true})
+	assert.Equal(t, http.StatusOK, code)
+
+	// при переходе на следующую дату отметки снимаются
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240202", ret["date"])
+	assert.Equal(t, map[string]any{"done": float64(0), "total": float64(2)}, ret["progress"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/complete?force=true", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240209", ret["date"])
+
+	// пункт удаляется вместе с вложенными
+	resp, _ = callV2(t, srv, http.MethodDelete, "/api/v2/tasks/"+id+"/checklist/1", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tasks/"+id+"/checklist", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []any{}, ret["items"])
+	code, _ = callJSON(t, srv, http.MethodDelete, "/api/task/checklist?id="+id+"&item=2", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestChecklist(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date": time.Now().Format(`20060102`), "title": "Собрать вещи",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	ret, err = postJSON("api/task/checklist?id="+id, map[string]any{"text": "Паспорт"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, id, ret["task_id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "task has open checklist items", ret["error"])
+
+	// разовая задача уходит в архив, а потом в корзину вместе с чек-листом
+	ret, err = postJSON("api/task/done?force=true&id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	var num int
+	assert.NoError(t, db.Get(&num, "SELECT count(*) FROM checklist_items WHERE task_id=?", id))
+	assert.Equal(t, 1, num)
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateEnd(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "d 1 count=0", ""},
+		{"20240101", "d 1 count=x", ""},
+		{"20240101", "d 1 count=2 until=20240301", ""},
+		{"20240101", "d 1 until=2024", ""},
+		{"20240101", "d 1 foo=1", ""},
+		{"20240101", "d 1 count=2 3", ""},
+		{"20240120", "d 2 count=3", ""},
+		{"20240122", "d 2 count=3", ""},
+		{"20240124", "d 2 count=3", "20240128"},
+		{"20240101", "w 1 until=20240128", ""},
+		{"20240101", "w 1 until=20240129", "20240129"},
+		{"20240101", "w 1 /2 until=20240301", "20240129"},
+		{"20240201", "m -1 1,2 count=2", "20240229"},
+		{"20240101", "m -1 1,2 count=2", "20240131"},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+func TestDoneCount(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	for _, repeat := range []string{"d 1 count=3", "FREQ=DAILY;COUNT=3"} {
+		now := time.Now()
+		id := addTask(t, task{
+			date:   now.Format(`20060102`),
+			title:  "Ровно три раза",
+			repeat: repeat,
+		})
+
+		for i := 2; i > 0; i-- {
+			ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+			assert.NoError(t, err)
+			assert.Empty(t, ret)
+
+			var task Task
+			err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+			assert.NoError(t, err)
+			now = now.AddDate(0, 0, 1)
+			assert.Equal(t, now.Format(`20060102`), task.Date)
+			assert.Equal(t, int64(i), task.Remaining)
+		}
+
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+		notFoundTask(t, id)
+	}
+}
+
+func TestDoneUntil(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "До завтра",
+		repeat: "d 1 until=" + now.AddDate(0, 0, 1).Format(`20060102`),
+	})
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), task.Date)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
+
+func TestEditCount(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Три тренировки",
+		repeat: "d 2 count=3",
+	})
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var task Task
+	check := func(repeat string, remaining int64) {
+		_, err := postJSON("api/task", map[string]any{
+			"id":     id,
+			"date":   now.AddDate(0, 0, 2).Format(`20060102`),
+			"title":  "Тренировки",
+			"repeat": repeat,
+		}, http.MethodPut)
+		assert.NoError(t, err)
+		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, remaining, task.Remaining)
+	}
+	// правило не поменялось - счетчик остается
+	check("d 2 count=3", 2)
+	// новое правило - новый счетчик
+	check("d 2 count=5", 5)
+	check("d 2", 0)
+}
@@ -11,11 +11,22 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID         int64  `db:"id"`
+	Date       string `db:"date"`
+	Title      string `db:"title"`
+	Comment    string `db:"comment"`
+	Repeat     string `db:"repeat"`
+	Remaining  int64  `db:"remaining"`
+	RepeatMode string `db:"repeat_mode"`
+	DueTime    string `db:"due_time"`
+	TimeZone   string `db:"timezone"`
+	Created    string `db:"created_at"`
+	ListId     int64  `db:"list_id"`
+	Priority   string `db:"priority"`
+	Status     string `db:"status"`
+	Estimate   int64  `db:"estimate"`
+	Archived   string `db:"archived_at"`
+	Deleted    string `db:"deleted_at"`
 }
 
 func count(db *sqlx.DB) (int, error) {
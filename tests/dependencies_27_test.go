@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// функция возвращает заголовки задач
+func graphTitles(tasks []*db.Task) []string {
+	titles := []string{}
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+// проверки зависимостей, общие для всех хранилищ
+func checkDependencies(t *testing.T, store db.TaskStore) {
+	// фундамент -> стены -> крыша, стены -> окна
+	ids := make(map[string]int)
+	for _, title := range []string{"Фундамент", "Стены", "Крыша", "Окна", "Забор"} {
+		id, err := store.AddTask(&db.Task{Date: "20240126", Title: title})
+		assert.NoError(t, err)
+		ids[title] = int(id)
+	}
+	for _, v := range [][2]string{{"Стены", "Фундамент"}, {"Крыша", "Стены"}, {"Окна", "Стены"}} {
+		assert.NoError(t, store.AddDependency(ids[v[0]], ids[v[1]]))
+	}
+	// повторное добавление ничего не меняет
+	assert.NoError(t, store.AddDependency(ids["Крыша"], ids["Стены"]))
+	assert.ErrorIs(t, store.AddDependency(ids["Фундамент"], ids["Крыша"]), db.ErrDependencyCycle)
+	assert.ErrorIs(t, store.AddDependency(ids["Забор"], ids["Забор"]), db.ErrDependencyCycle)
+	assert.ErrorIs(t, store.AddDependency(ids["Забор"], 999999), db.ErrNotFound)
+
+	task, err := store.GetTask(strconv.Itoa(ids["Крыша"]))
+	if assert.NoError(t, err) {
+		assert.True(t, task.Blocked)
+	}
+	blocked, free := true, false
+	assert.Equal(t, []string{"Крыша", "Окна", "Стены"}, tagTitles(t, store, db.TaskQuery{Sort: db.SortTitle, Blocked: &blocked}))
+	assert.Equal(t, []string{"Забор", "Фундамент"}, tagTitles(t, store, db.TaskQuery{Sort: db.SortTitle, Blocked: &free}))
+
+	graph, err := store.TaskGraph(ids["Стены"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Фундамент"}, graphTitles(graph.Upstream))
+		assert.Equal(t, []string{"Крыша", "Окна"}, graphTitles(graph.Downstream))
+		assert.Len(t, graph.Edges, 3)
+		assert.True(t, graph.Downstream[0].Blocked)
+	}
+	graph, err = store.TaskGraph(ids["Крыша"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Стены", "Фундамент"}, graphTitles(graph.Upstream))
+		assert.Empty(t, graph.Downstream)
+		assert.Equal(t, []*db.Dependency{
+			{TaskId: ids["Стены"], DependsOn: ids["Фундамент"]},
+			{TaskId: ids["Крыша"], DependsOn: ids["Стены"]},
+		}, graph.Edges)
+	}
+	_, err = store.TaskGraph(999999)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// выполненный и перенесенный в архив фундамент больше никого не держит, но зависимость остается
+	assert.NoError(t, store.AdvanceTask(&db.Advance{TaskId: ids["Фундамент"], Archived: "2024-01-26T12:00:00Z"}))
+	task, err = store.GetTask(strconv.Itoa(ids["Стены"]))
+	if assert.NoError(t, err) {
+		assert.False(t, task.Blocked)
+	}
+	assert.NoError(t, store.DelDependency(ids["Стены"], ids["Фундамент"]))
+
+	// повторяющаяся задача держит только до выполнения текущего повторения
+	assert.NoError(t, store.AddDependency(ids["Забор"], ids["Окна"]))
+	assert.NoError(t, store.UpdTask(&db.Task{Id: ids["Окна"], Date: "20240127", Title: "Окна", Repeat: "d 7"}))
+	task, err = store.GetTask(strconv.Itoa(ids["Забор"]))
+	if assert.NoError(t, err) {
+		assert.False(t, task.Blocked)
+	}
+	assert.NoError(t, store.UpDateTask("20240126", 0, strconv.Itoa(ids["Окна"])))
+	task, err = store.GetTask(strconv.Itoa(ids["Забор"]))
+	if assert.NoError(t, err) {
+		assert.True(t, task.Blocked)
+	}
+	assert.NoError(t, store.SetStatus(strconv.Itoa(ids["Окна"]), db.StatusDone))
+	task, err = store.GetTask(strconv.Itoa(ids["Забор"]))
+	if assert.NoError(t, err) {
+		assert.False(t, task.Blocked)
+	}
+	assert.NoError(t, store.DelDependency(ids["Забор"], ids["Окна"]))
+	assert.NoError(t, store.DelDependency(ids["Окна"], ids["Стены"]))
+	assert.ErrorIs(t, store.DelDependency(ids["Окна"], ids["Стены"]), db.ErrDependencyNotFound)
+
+	// удаленная задача никого не держит
+	assert.NoError(t, store.DelTask(strconv.Itoa(ids["Стены"])))
+	task, err = store.GetTask(strconv.Itoa(ids["Крыша"]))
+	if assert.NoError(t, err) {
+		assert.False(t, task.Blocked)
+	}
+	// цикл через задачу в корзине тоже не добавляется: после возврата из корзины он бы замкнулся
+	for _, title := range []string{"Эскиз", "Смета", "Договор"} {
+		id, err := store.AddTask(&db.Task{Date: "20240126", Title: title})
+		assert.NoError(t, err)
+		ids[title] = int(id)
+	}
+	assert.NoError(t, store.AddDependency(ids["Эскиз"], ids["Смета"]))
+	assert.NoError(t, store.AddDependency(ids["Смета"], ids["Договор"]))
+	assert.NoError(t, store.TrashTask(strconv.Itoa(ids["Смета"]), "2024-01-26T12:00:00Z"))
+	assert.ErrorIs(t, store.AddDependency(ids["Договор"], ids["Эскиз"]), db.ErrDependencyCycle)
+	assert.NoError(t, store.RestoreTask(strconv.Itoa(ids["Смета"])))
+	graph, err = store.TaskGraph(ids["Эскиз"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Смета", "Договор"}, graphTitles(graph.Upstream))
+		assert.Empty(t, graph.Downstream)
+	}
+}
+
+func TestDependenciesStore(t *testing.T) {
+	eachStore(t, checkDependencies)
+}
+
+func TestDependenciesAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	code, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Купить краску"})
+	assert.Equal(t, http.StatusOK, code)
+	first, _ := ret["id"].(string)
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Покрасить забор", "repeat": "d 7"})
+	assert.Equal(t, http.StatusOK, code)
+	second, _ := ret["id"].(string)
+
+	resp, ret := callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+second+"/dependencies", `{"depends_on": "`+first+`"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, true, ret["blocked"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+first+"/dependencies", `{"depends_on": "`+second+`"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "dependency would create a cycle", ret["message"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+first+"/dependencies", `{"depends_on": "9"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]any{"field": "depends_on"}, ret["details"])
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task/dependency?id="+first+"&on=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "on must be a task id", ret["error"])
+
+	// задача, которая ждет другую, видна в списке с пометкой и не выполняется
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?blocked=false", nil)
+	assert.Equal(t, float64(1), ret["total"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?blocked=true", nil)
+	assert.Equal(t, float64(1), ret["total"])
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task/done?id="+second, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "task is blocked by unfinished tasks", ret["error"])
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+second+"/complete", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task/graph?id="+first, nil)
+	assert.Equal(t, []any{}, ret["upstream"])
+	if downstream, _ := ret["downstream"].([]any); assert.Len(t, downstream, 1) {
+		assert.Equal(t, "Покрасить забор", downstream[0].(map[string]any)["title"])
+	}
+	assert.Equal(t, []any{map[string]any{"task_id": second, "depends_on": first}}, ret["edges"])
+
+	// выполненная задача уходит в архив и больше никого не держит
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/done?id="+first, nil)
+	assert.Equal(t, http.StatusOK, code)
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+second+"/complete", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, ret["blocked"])
+
+	// повторяющаяся задача после выполнения держит только до следующего повторения, зависимость остается
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Отчет"})
+	assert.Equal(t, http.StatusOK, code)
+	third, _ := ret["id"].(string)
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/dependency?id="+third+"&on="+second, nil)
+	assert.Equal(t, http.StatusOK, code)
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+second+"/complete", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tasks/"+third, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, ret["blocked"])
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tasks/"+third+"/graph", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if upstream, _ := ret["upstream"].([]any); assert.Len(t, upstream, 1) {
+		assert.Equal(t, "Покрасить забор", upstream[0].(map[string]any)["title"])
+	}
+	resp, _ = callV2(t, srv, http.MethodDelete, "/api/v2/tasks/"+third+"/dependencies/"+second, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestDependencies(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	ret, err := postJSON("api/task", map[string]any{"date": today, "title": "Купить краску"}, http.MethodPost)
+	assert.NoError(t, err)
+	first, _ := ret["id"].(string)
+	ret, err = postJSON("api/task", map[string]any{"date": today, "title": "Покрасить забор"}, http.MethodPost)
+	assert.NoError(t, err)
+	second, _ := ret["id"].(string)
+
+	ret, err = postJSON("api/task/dependency?id="+second+"&on="+first, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/done?id="+second, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "task is blocked by unfinished tasks", ret["error"])
+
+	// задача в корзине никого не держит, но зависимость остается до окончательного удаления
+	_, err = postJSON("api/task?id="+first, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	ret, err = postJSON("api/task?id="+second, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["blocked"])
+	var num int
+	assert.NoError(t, db.Get(&num, "SELECT count(*) FROM task_dependencies WHERE depends_on=?", first))
+	assert.Equal(t, 1, num)
+	_, err = postJSON("api/task?id="+second, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Exception struct {
+	TaskID  int64  `db:"task_id"`
+	Date    string `db:"date"`
+	NewDate string `db:"new_date"`
+}
+
+func TestSkip(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: "Разовая задача",
+	})
+	ret, err := postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "d 7",
+	})
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var tsk Task
+	err = db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 7).Format(`20060102`), tsk.Date)
+
+	var exceptions []Exception
+	err = db.Select(&exceptions, `SELECT * FROM exceptions WHERE task_id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, []Exception{{TaskID: tsk.ID, Date: now.Format(`20060102`)}}, exceptions)
+
+	// пропуск последнего повторения заканчивает серию, и задача уходит в архив вместе с исключениями
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Два раза",
+		repeat: "d 1 count=2",
+	})
+	for i := 0; i < 2; i++ {
+		ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	notFoundTask(t, id)
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.NotEmpty(t, tsk.Archived)
+	err = db.Select(&exceptions, `SELECT * FROM exceptions WHERE task_id=?`, id)
+	assert.NoError(t, err)
+	assert.Len(t, exceptions, 2)
+}
+
+func TestReschedule(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Каждый день",
+		repeat: "d 1",
+	})
+	ret, err := postJSON("api/task/reschedule?id="+id+"&date=2024", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// переносим сегодняшнее повторение на послезавтра
+	ret, err = postJSON("api/task/reschedule?id="+id+"&date="+now.AddDate(0, 0, 2).Format(`20060102`),
+		nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var tsk Task
+	err = db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), tsk.Date)
+
+	// после выполнения серия продолжается от исходной даты
+	for i := 1; i <= 3; i++ {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		err = db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, now.AddDate(0, 0, i).Format(`20060102`), tsk.Date)
+	}
+
+	// перенос обратно на исходную дату убирает исключение
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Раз в неделю",
+		repeat: "d 7",
+	})
+	for _, date := range []string{now.AddDate(0, 0, 1).Format(`20060102`), now.Format(`20060102`)} {
+		ret, err = postJSON("api/task/reschedule?id="+id+"&date="+date, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	var exceptions []Exception
+	err = db.Select(&exceptions, `SELECT * FROM exceptions WHERE task_id=?`, id)
+	assert.NoError(t, err)
+	assert.Empty(t, exceptions)
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/mrScorpio/finalTask/internal/handlers"
+	"github.com/mrScorpio/finalTask/internal/nextdate"
+	"github.com/mrScorpio/finalTask/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// функция запускает в процессе отдельный сервер с хранилищем в памяти и часами, которые стоят на now
+func newTestServer(t *testing.T, cfg config.Config, now time.Time) *httptest.Server {
+	h := handlers.New(db.NewMemory(), cfg, nextdate.FixedClock(now), nil)
+	srv := httptest.NewServer(server.NewServer(h, nil, "").Serv.Handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func callJSON(t *testing.T, srv *httptest.Server, method, path string, values map[string]any) (int, map[string]any) {
+	data, err := json.Marshal(values)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader(data))
+	assert.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var m map[string]any
+	json.Unmarshal(body, &m)
+	return resp.StatusCode, m
+}
+
+func TestHandlersClock(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	for _, v := range []struct {
+		date   string
+		repeat string
+		want   string
+	}{
+		{"20240120", "", "20240126"},
+		{"20240120", "d 3", "20240129"},
+		{"20240120", "w 5", "20240202"},
+		{"", "d 3", "20240126"},
+		{"20240201", "d 3", "20240201"},
+	} {
+		_, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{
+			"date":   v.date,
+			"title":  "Задача",
+			"repeat": v.repeat,
+		})
+		assert.Equal(t, v.want, ret["date"], `{%q, %q, %q}`, v.date, v.repeat, v.want)
+	}
+
+	// now по умолчанию тоже берется из часов сервера
+	resp, err := srv.Client().Get(srv.URL + "/api/nextdate?date=20240120&repeat=d+3")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "20240129", string(body))
+}
+
+func TestHandlersIsolated(t *testing.T) {
+	now := time.Now()
+	first := newTestServer(t, config.Config{}, now)
+	second := newTestServer(t, config.Config{}, now)
+
+	_, ret := callJSON(t, first, http.MethodPost, "/api/task", map[string]any{"title": "Только в первом"})
+	id := fmt.Sprint(ret["id"])
+
+	_, ret = callJSON(t, first, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, "Только в первом", ret["title"])
+	_, ret = callJSON(t, second, http.MethodGet, "/api/task?id="+id, nil)
+	assert.NotEmpty(t, ret["error"])
+
+	// пароль задается в настройках каждого сервера
+	secured := newTestServer(t, config.Config{Password: "secret"}, now)
+	code, _ := callJSON(t, secured, http.MethodGet, "/api/tasks", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = callJSON(t, first, http.MethodGet, "/api/tasks", nil)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestHandlersDates(t *testing.T) {
+	// пояс и календарь праздников у каждого сервера свои
+	tokyo := config.Config{TimeZone: "Asia/Tokyo", Holidays: []string{"testdata/holidays.ics"}}
+	if !assert.NoError(t, tokyo.LoadDates()) {
+		return
+	}
+	london := config.Config{TimeZone: "Europe/London"}
+	if !assert.NoError(t, london.LoadDates()) {
+		return
+	}
+	now := time.Date(2024, 1, 26, 22, 0, 0, 0, time.UTC)
+	first := newTestServer(t, tokyo, now)
+	second := newTestServer(t, london, now)
+
+	_, ret := callJSON(t, first, http.MethodPost, "/api/task", map[string]any{"title": "Без даты"})
+	_, ret = callJSON(t, first, http.MethodGet, "/api/task?id="+fmt.Sprint(ret["id"]), nil)
+	assert.Equal(t, "20240127", ret["date"])
+	_, ret = callJSON(t, second, http.MethodPost, "/api/task", map[string]any{"title": "Без даты"})
+	_, ret = callJSON(t, second, http.MethodGet, "/api/task?id="+fmt.Sprint(ret["id"]), nil)
+	assert.Equal(t, "20240126", ret["date"])
+
+	// 8 марта - праздник только в календаре первого сервера
+	for srv, want := range map[*httptest.Server]string{first: "20240311", second: "20240308"} {
+		resp, err := srv.Client().Get(srv.URL + "/api/nextdate?now=20240307&date=20240307&repeat=bd+1")
+		if !assert.NoError(t, err) {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(body))
+	}
+
+	cfg := config.Config{TimeZone: "Mars/Olympus"}
+	assert.ErrorContains(t, cfg.LoadDates(), `unknown time zone "Mars/Olympus"`)
+	cfg = config.Config{Holidays: []string{"testdata/broken.json"}}
+	assert.ErrorContains(t, cfg.LoadDates(), "can't load holidays from testdata/broken.json")
+}
@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// проверки архива и истории выполнений, общие для всех хранилищ
+func checkHistory(t *testing.T, store db.TaskStore) {
+	onceId, err := store.AddTask(&db.Task{Date: "20240126", Title: "Отчет"})
+	assert.NoError(t, err)
+	repeatId, err := store.AddTask(&db.Task{Date: "20240126", Title: "Зарядка", Repeat: "d 1", Remaining: 3})
+	assert.NoError(t, err)
+	once, repeat := strconv.Itoa(int(onceId)), strconv.Itoa(int(repeatId))
+
+	// выполнения: повторяющаяся задача переходит на следующую дату, разовая уходит в архив
+	assert.NoError(t, store.AdvanceTask(&db.Advance{TaskId: int(repeatId), Date: "20240127", Remaining: 2,
+		Completion: &db.Completion{TaskId: int(repeatId), Date: "20240126", Origin: "20240126", Remaining: 3,
+			CompletedAt: "2024-01-26T08:00:00Z"}}))
+	assert.NoError(t, store.AdvanceTask(&db.Advance{TaskId: int(onceId), Archived: "2024-01-26T09:00:00Z",
+		Completion: &db.Completion{TaskId: int(onceId), Date: "20240126", Origin: "20240126",
+			CompletedAt: "2024-01-26T09:00:00Z"}}))
+	assert.ErrorIs(t, store.AdvanceTask(&db.Advance{TaskId: int(onceId), Archived: "2024-01-26T10:00:00Z"}), db.ErrNotFound)
+	// задача из архива не меняется, как и не читается
+	assert.ErrorIs(t, store.UpdTask(&db.Task{Id: int(onceId), Date: "20240127", Title: "Отчет"}), db.ErrNotFound)
+	assert.ErrorIs(t, store.UpDateTask("20240127", 0, once), db.ErrNotFound)
+	assert.ErrorIs(t, store.SetStatus(once, db.StatusDone), db.ErrNotFound)
+	assert.ErrorIs(t, store.AddException(&db.Exception{TaskId: int(onceId), Date: "20240126"}), db.ErrNotFound)
+
+	// задача из архива не видна среди задач, но ищется с фильтром archived
+	_, err = store.GetTask(once)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.Equal(t, []string{"Зарядка"}, tagTitles(t, store, db.TaskQuery{}))
+	assert.Equal(t, []string{"Отчет"}, tagTitles(t, store, db.TaskQuery{Archived: true}))
+
+	completions, err := store.Completions("", "", 10)
+	if assert.NoError(t, err) && assert.Len(t, completions, 2) {
+		assert.Equal(t, "Отчет", completions[0].Title)
+		assert.Equal(t, "Зарядка", completions[1].Title)
+		assert.Equal(t, "2024-01-26T08:00:00Z", completions[1].CompletedAt)
+	}
+	completions, err = store.Completions("20240127", "", 10)
+	if assert.NoError(t, err) {
+		assert.Empty(t, completions)
+	}
+
+	// отмена возвращает задачу из архива
+	last, err := store.LastCompletion()
+	if assert.NoError(t, err) {
+		assert.NoError(t, store.UndoCompletion(last))
+		assert.ErrorIs(t, store.UndoCompletion(last), db.ErrCompletionNotFound)
+	}
+	assert.Equal(t, []string{"Отчет", "Зарядка"}, tagTitles(t, store, db.TaskQuery{}))
+
+	// и возвращает повторяющуюся задачу на прежнюю дату с прежним счетчиком
+	last, err = store.LastCompletion()
+	if assert.NoError(t, err) {
+		assert.NoError(t, store.UndoCompletion(last))
+	}
+	task, err := store.GetTask(repeat)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "20240126", task.Date)
+		assert.Equal(t, 3, task.Remaining)
+	}
+	_, err = store.LastCompletion()
+	assert.ErrorIs(t, err, db.ErrCompletionNotFound)
+
+	// переход на следующее повторение записывает выполнение с колонкой и отметками чек-листа до него,
+	// а отмена их возвращает
+	itemId, err := store.AddChecklistItem(&db.ChecklistItem{TaskId: int(repeatId), Text: "Разминка", Done: true})
+	assert.NoError(t, err)
+	_, err = store.AddChecklistItem(&db.ChecklistItem{TaskId: int(repeatId), Text: "Бег"})
+	assert.NoError(t, err)
+	assert.NoError(t, store.SetStatus(repeat, db.StatusInProgress))
+	assert.NoError(t, store.AdvanceTask(&db.Advance{TaskId: int(repeatId), Date: "20240127", Remaining: 2,
+		Completion: &db.Completion{TaskId: int(repeatId), Date: "20240126", Origin: "20240126", Remaining: 3,
+			Status: db.StatusInProgress, CompletedAt: "2024-01-26T08:00:00Z"}}))
+	task, err = store.GetTask(repeat)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "20240127", task.Date)
+		assert.Equal(t, 2, task.Remaining)
+		assert.Equal(t, db.StatusTodo, task.Status)
+		assert.Equal(t, &db.Progress{Done: 0, Total: 2}, task.Progress)
+	}
+	last, err = store.LastCompletion()
+	if assert.NoError(t, err) {
+		assert.Equal(t, db.StatusInProgress, last.Status)
+		assert.Equal(t, []int{int(itemId)}, last.Checked)
+		assert.NoError(t, store.UndoCompletion(last))
+	}
+	task, err = store.GetTask(repeat)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "20240126", task.Date)
+		assert.Equal(t, 3, task.Remaining)
+		assert.Equal(t, db.StatusInProgress, task.Status)
+		assert.Equal(t, &db.Progress{Done: 1, Total: 2}, task.Progress)
+	}
+
+	// если переход не записался, выполнения в истории тоже нет
+	assert.ErrorIs(t, store.AdvanceTask(&db.Advance{TaskId: 999999, Archived: "2024-01-26T09:00:00Z",
+		Completion: &db.Completion{TaskId: 999999, Date: "20240126", Origin: "20240126"}}), db.ErrNotFound)
+	_, err = store.LastCompletion()
+	assert.ErrorIs(t, err, db.ErrCompletionNotFound)
+
+	// выполнения удаляются вместе с задачей
+	assert.NoError(t, store.AdvanceTask(&db.Advance{TaskId: int(onceId), Archived: "2024-01-26T09:00:00Z",
+		Completion: &db.Completion{TaskId: int(onceId), Date: "20240126", Origin: "20240126"}}))
+	assert.NoError(t, store.DelTask(once))
+	completions, err = store.Completions("", "", 10)
+	if assert.NoError(t, err) {
+		assert.Empty(t, completions)
+	}
+}
+
+func TestHistoryStore(t *testing.T) {
+	eachStore(t, checkHistory)
+}
+
+// функция возвращает заголовки задач из истории выполнений
+func historyTitles(ret map[string]any) []string {
+	titles := []string{}
+	completions, _ := ret["completions"].([]any)
+	for _, c := range completions {
+		title, _ := c.(map[string]any)["title"].(string)
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+func TestHistoryAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	_, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Отчет"})
+	once, _ := ret["id"].(string)
+	_, ret = callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Зарядка", "repeat": "d 1"})
+	repeat, _ := ret["id"].(string)
+
+	// выполнение перенесенного повторения
+	code, _ := callJSON(t, srv, http.MethodPost, "/api/task/reschedule?id="+repeat+"&date=20240128", nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/done?id="+repeat, nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+repeat, nil)
+	assert.Equal(t, "20240127", ret["date"])
+
+	// разовая задача уходит в архив
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/done?id="+once, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = callJSON(t, srv, http.MethodGet, "/api/task?id="+once, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?archived=true", nil)
+	assert.Equal(t, float64(1), ret["total"])
+	code, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?archived=да", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "archived must be true or false", ret["error"])
+
+	code, ret = callJSON(t, srv, http.MethodGet, "/api/history", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Отчет", "Зарядка"}, historyTitles(ret))
+	if completions, _ := ret["completions"].([]any); assert.Len(t, completions, 2) {
+		c := completions[1].(map[string]any)
+		assert.Equal(t, repeat, c["task_id"])
+		assert.Equal(t, "20240128", c["date"])
+		assert.Equal(t, "2024-01-26T12:00:00Z", c["completed_at"])
+	}
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/history?from=20240127&to=20240128", nil)
+	assert.Equal(t, []string{"Зарядка"}, historyTitles(ret))
+	code, ret = callJSON(t, srv, http.MethodGet, "/api/history?to=завтра", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "to must be a date 20060102", ret["error"])
+
+	// отмена возвращает разовую задачу из архива
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/history/undo", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, ret)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+once, nil)
+	assert.Equal(t, "Отчет", ret["title"])
+
+	// а повторение - на перенесенную дату, и после выполнения серия идет как раньше
+	resp, ret := callV2(t, srv, http.MethodPost, "/api/v2/history/undo", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240128", ret["date"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+repeat+"/complete", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240127", ret["date"])
+
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/history?limit=1", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"Зарядка"}, historyTitles(ret))
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/history?limit=0", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]any{"field": "limit"}, ret["details"])
+
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/history/undo", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/history/undo", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "not_found", ret["code"])
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/history/undo", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "no completions", ret["error"])
+
+	// отмена возвращает колонку и отметки чек-листа, которые сняло выполнение
+	callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+repeat+"/checklist", `{"text": "Разминка"}`)
+	callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+repeat+"/checklist", `{"text": "Бег"}`)
+	resp, _ = callV2(t, srv, http.MethodPut, "/api/v2/tasks/"+repeat+"/checklist/1", `{"done": true}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+repeat+"/status", `{"status": "in_progress"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+repeat+"/complete?force=true", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "todo", ret["status"])
+	assert.Equal(t, map[string]any{"done": float64(0), "total": float64(2)}, ret["progress"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/history/undo", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240128", ret["date"])
+	assert.Equal(t, "in_progress", ret["status"])
+	assert.Equal(t, map[string]any{"done": float64(1), "total": float64(2)}, ret["progress"])
+}
+
+func TestHistory(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	ret, err := postJSON("api/task", map[string]any{"date": today, "title": "Архив"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.NotEmpty(t, task.Archived)
+	var num int
+	assert.NoError(t, db.Get(&num, `SELECT count(*) FROM completions WHERE task_id=? AND date=?`, id, today))
+	assert.Equal(t, 1, num)
+
+	ret, err = postJSON("api/history/undo", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Empty(t, task.Archived)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
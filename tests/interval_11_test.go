@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateInterval(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "d 7 /2", ""},
+		{"20240101", "w 1 /0", ""},
+		{"20240101", "w 1 /101", ""},
+		{"20240101", "w 1 /x", ""},
+		{"20240101", "w /2 1", ""},
+		{"20240101", "y /2 /3", ""},
+		{"20240101", "w 1,4 /2", "20240129"},
+		{"20240108", "w 1,4 /2", "20240205"},
+		{"20240115", "m 15 /3", "20240415"},
+		{"20231031", "m -1 /2", "20240229"},
+		{"20240101", "mw 1:1 /2", "20240304"},
+		{"20220301", "y /2", "20240301"},
+		{"20230301", "y /2", "20250301"},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+func TestDoneInterval(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	weekDay := int(now.Weekday())
+	if weekDay == 0 {
+		weekDay = 7
+	}
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Раз в две недели",
+		repeat: fmt.Sprintf("w %d /2", weekDay),
+	})
+
+	for i := 0; i < 3; i++ {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var task Task
+		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		now = now.AddDate(0, 0, 14)
+		assert.Equal(t, task.Date, now.Format(`20060102`))
+	}
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// функция возвращает имена и количество задач всех списков по порядку
+func listCounts(t *testing.T, store db.TaskStore) map[string]int {
+	lists, err := store.Lists()
+	assert.NoError(t, err)
+	ret := make(map[string]int, len(lists))
+	for _, list := range lists {
+		ret[list.Name] = list.Count
+	}
+	return ret
+}
+
+// проверки списков задач, общие для всех хранилищ
+func checkLists(t *testing.T, store db.TaskStore) {
+	lists, err := store.Lists()
+	assert.NoError(t, err)
+	assert.Equal(t, []*db.List{{Id: db.DefaultList, Name: "Входящие"}}, lists)
+
+	work, err := store.AddList(&db.List{Name: "Работа"})
+	assert.NoError(t, err)
+	home, err := store.AddList(&db.List{Name: "Дом", Colour: "#00aa00"})
+	assert.NoError(t, err)
+	_, err = store.AddList(&db.List{Name: "Работа"})
+	assert.ErrorIs(t, err, db.ErrListExists)
+	list, err := store.GetList(int(home))
+	if assert.NoError(t, err) {
+		assert.Equal(t, &db.List{Id: int(home), Name: "Дом", Colour: "#00aa00", Position: 2}, list)
+	}
+	_, err = store.GetList(999999)
+	assert.ErrorIs(t, err, db.ErrListNotFound)
+
+	// задача без списка попадает в список по умолчанию
+	ids := make(map[string]string)
+	for _, v := range []struct {
+		title  string
+		listId int
+		tags   []string
+	}{
+		{"Полить цветы", 0, nil},
+		{"Отчет", int(work), nil},
+		{"Созвон", int(work), []string{"call"}},
+	} {
+		id, err := store.AddTask(&db.Task{Date: "20240126", Title: v.title, ListId: v.listId, Tags: v.tags})
+		assert.NoError(t, err)
+		ids[v.title] = strconv.FormatInt(id, 10)
+	}
+	task, err := store.GetTask(ids["Полить цветы"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, db.DefaultList, task.ListId)
+	}
+	assert.NoError(t, store.AddException(&db.Exception{TaskId: atoi(ids["Созвон"]), Date: "20240126"}))
+	assert.Equal(t, map[string]int{"Входящие": 1, "Работа": 2, "Дом": 0}, listCounts(t, store))
+	assert.Equal(t, []string{"Отчет", "Созвон"}, tagTitles(t, store, db.TaskQuery{Sort: db.SortTitle, ListId: int(work)}))
+
+	// перенос задачи
+	assert.NoError(t, store.MoveTask(ids["Отчет"], int(home)))
+	assert.ErrorIs(t, store.MoveTask(ids["Отчет"], 999999), db.ErrListNotFound)
+	assert.ErrorIs(t, store.MoveTask("999999", int(home)), db.ErrNotFound)
+	assert.Equal(t, []string{"Отчет"}, tagTitles(t, store, db.TaskQuery{ListId: int(home)}))
+
+	// списки идут по месту, а при равном месте - по айди
+	assert.NoError(t, store.UpdList(&db.List{Id: int(home), Name: "Дом"}))
+	assert.ErrorIs(t, store.UpdList(&db.List{Id: int(home), Name: "Работа"}), db.ErrListExists)
+	assert.ErrorIs(t, store.UpdList(&db.List{Id: 999999, Name: "Дача"}), db.ErrListNotFound)
+	lists, err = store.Lists()
+	assert.NoError(t, err)
+	var names []string
+	for _, list := range lists {
+		names = append(names, list.Name)
+	}
+	assert.Equal(t, []string{"Входящие", "Дом", "Работа"}, names)
+
+	// удаление с переносом задач и вместе с задачами
+	assert.ErrorIs(t, store.DelList(db.DefaultList, 0, ""), db.ErrDefaultList)
+	assert.ErrorIs(t, store.DelList(999999, db.DefaultList, ""), db.ErrListNotFound)
+	assert.NoError(t, store.DelList(int(home), db.DefaultList, ""))
+	task, err = store.GetTask(ids["Отчет"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, db.DefaultList, task.ListId)
+	}
+	// задачи удаленного вместе с ними списка уходят в корзину, а возвращаются в список по умолчанию
+	assert.NoError(t, store.DelList(int(work), 0, "2024-01-26T12:00:00Z"))
+	_, err = store.GetTask(ids["Созвон"])
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	tags, err := store.Tags()
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+	assert.Equal(t, map[string]int{"Входящие": 2}, listCounts(t, store))
+	trash, err := store.Trash(10)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Созвон"}, graphTitles(trash))
+		assert.Equal(t, "2024-01-26T12:00:00Z", trash[0].Deleted)
+	}
+	assert.NoError(t, store.RestoreTask(ids["Созвон"]))
+	task, err = store.GetTask(ids["Созвон"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, db.DefaultList, task.ListId)
+		assert.Equal(t, []string{"call"}, task.Tags)
+	}
+	assert.Equal(t, map[string]int{"Входящие": 3}, listCounts(t, store))
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func TestListsStore(t *testing.T) {
+	eachStore(t, checkLists)
+}
+
+func TestListsAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	resp, ret := callV2(t, srv, http.MethodPost, "/api/v2/lists", `{"name": " Покупки ", "colour": "#FFAA00"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, map[string]any{"id": "2", "name": "Покупки", "colour": "#ffaa00", "position": float64(1), "count": float64(0)}, ret)
+	assert.Equal(t, "/api/v2/lists/2", resp.Header.Get("Location"))
+
+	code, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"title": "Молоко", "list_id": "2"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "2", ret["list_id"])
+	id, _ := ret["id"].(string)
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"title": "Полить цветы"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1", ret["list_id"])
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks", `{"title": "Хлеб", "list_id": "7"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]any{"field": "list_id"}, ret["details"])
+
+	// список задач одного списка; без поля list_id задача остается в своем списке
+	code, _ = callJSON(t, srv, http.MethodPut, "/api/task", map[string]any{"id": id, "date": "20240126", "title": "Молоко 2 л"})
+	assert.Equal(t, http.StatusOK, code)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?list=2", nil)
+	assert.Equal(t, float64(1), ret["total"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?list=покупки", nil)
+	assert.NotEmpty(t, ret["error"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/lists", nil)
+	assert.Len(t, ret["lists"], 2)
+
+	// перенос задачи
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/move", `{"list_id": "1"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", ret["list_id"])
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/move?id="+id+"&list=2", nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	// удаление: задачи переносятся в список to или в корзину
+	resp, ret = callV2(t, srv, http.MethodDelete, "/api/v2/lists/2?to=9", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]any{"field": "to"}, ret["details"])
+	resp, ret = callV2(t, srv, http.MethodDelete, "/api/v2/lists/2?to=2", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodDelete, "/api/v2/lists/1", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "conflict", ret["code"])
+	resp, _ = callV2(t, srv, http.MethodDelete, "/api/v2/lists/2", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", ret["list_id"])
+
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/lists", `{"name": "Покупки"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/move", `{"list_id": "3"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	code, _ = callJSON(t, srv, http.MethodDelete, "/api/list?id=3&tasks=cascade", nil)
+	assert.Equal(t, http.StatusOK, code)
+	resp, _ = callV2(t, srv, http.MethodGet, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/trash/"+id+"/restore", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", ret["list_id"])
+}
+
+func TestLists(t *testing.T) {
+	ret, err := postJSON("api/lists", nil, http.MethodGet)
+	assert.NoError(t, err)
+	lists, _ := ret["lists"].([]any)
+	if assert.NotEmpty(t, lists) {
+		assert.Equal(t, "1", lists[0].(map[string]any)["id"])
+	}
+
+	ret, err = postJSON("api/list", map[string]any{"name": "Тестовый список"}, http.MethodPost)
+	assert.NoError(t, err)
+	list, _ := ret["id"].(string)
+	assert.NotEmpty(t, list)
+	ret, err = postJSON("api/task", map[string]any{
+		"date": time.Now().Format(`20060102`), "title": "Задача в списке", "list_id": list,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, list, ret["list_id"])
+
+	ret, err = postJSON("api/tasks?list="+list, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), ret["total"])
+
+	// список удаляется, а задача уходит в корзину
+	_, err = postJSON("api/list?tasks=cascade&id="+list, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	ret, err = postJSON("api/list?id="+list, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, "list not found", ret["error"])
+	ret, err = postJSON("api/tasks?list="+list, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), ret["total"])
+}
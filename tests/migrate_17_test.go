@@ -0,0 +1,26 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	files, err := filepath.Glob("../internal/db/migrations/sqlite/*.up.sql")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	// сервер при запуске применяет все шаги по порядку
+	var versions []int
+	err = db.Select(&versions, `SELECT version FROM schema_migrations ORDER BY version`)
+	assert.NoError(t, err)
+	assert.Len(t, versions, len(files))
+	for i, v := range versions {
+		assert.Equal(t, i+1, v)
+	}
+}
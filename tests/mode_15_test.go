@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addTaskMode(t *testing.T, task task, mode string) string {
+	ret, err := postJSON("api/task", map[string]any{
+		"date":        task.date,
+		"title":       task.title,
+		"comment":     task.comment,
+		"repeat":      task.repeat,
+		"repeat_mode": mode,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["id"])
+	id := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, id)
+	return id
+}
+
+func TestRepeatMode(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"date":        now.Format(`20060102`),
+		"title":       "Неизвестный режим",
+		"repeat":      "d 3",
+		"repeat_mode": "sometimes",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// без режима задача повторяется по расписанию
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Оплатить аренду",
+		repeat: "d 30",
+	})
+	var tsk Task
+	err = db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed", tsk.RepeatMode)
+
+	id = addTaskMode(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "d 3",
+	}, "after-completion")
+	ret, err = getTask(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "after-completion", ret["repeat_mode"])
+
+	// при изменении без режима он остается прежним
+	_, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   now.AddDate(0, 0, 2).Format(`20060102`),
+		"title":  "Полить цветы",
+		"repeat": "d 3",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	err = db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "after-completion", tsk.RepeatMode)
+}
+
+func TestDoneAfterCompletion(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	for _, v := range []struct {
+		mode string
+		want string
+	}{
+		// по расписанию - от даты задачи, после выполнения - от сегодняшнего дня
+		{"fixed", now.AddDate(0, 0, 5).Format(`20060102`)},
+		{"after-completion", now.AddDate(0, 0, 3).Format(`20060102`)},
+	} {
+		id := addTaskMode(t, task{
+			date:   now.AddDate(0, 0, 2).Format(`20060102`),
+			title:  "Полить цветы",
+			repeat: "d 3",
+		}, v.mode)
+
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var task Task
+		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, task.Date, v.mode)
+	}
+}
+
+func getTask(id string) (map[string]any, error) {
+	return postJSON("api/task?id="+id, nil, http.MethodGet)
+}
@@ -74,6 +74,20 @@ func TestNextDate(t *testing.T) {
 		{"20240126", "w 7", "20240128"},
 		{"20230126", "w 4,5", "20240201"},
 		{"20230226", "w 8,4,5", ""},
+		{"20240101", "mw 2:2", "20240213"},
+		{"20240101", "mw -1:5", "20240223"},
+		{"20240101", "mw 1:1", "20240205"},
+		{"20240101", "mw 2:2,-1:5", "20240213"},
+		{"20240101", "mw 1:1 3,6", "20240304"},
+		{"20240101", "mw 5:4", "20240229"},
+		{"20240101", "mw -2:7", "20240218"},
+		{"20240101", "mw", ""},
+		{"20240101", "mw 2", ""},
+		{"20240101", "mw 6:1", ""},
+		{"20240101", "mw 0:1", ""},
+		{"20240101", "mw -6:1", ""},
+		{"20240101", "mw 1:8", ""},
+		{"20240101", "mw 2:2 13", ""},
 	}
 	check()
 }
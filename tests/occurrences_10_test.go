@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type occurrences struct {
+	date   string
+	repeat string
+	count  string
+	until  string
+	want   []string
+}
+
+func TestOccurrences(t *testing.T) {
+	tbl := []occurrences{
+		{"20240101", "d 10", "3", "", []string{"20240131", "20240210", "20240220"}},
+		{"20240101", "d 10", "5", "20240215", []string{"20240131", "20240210"}},
+		{"20240101", "w 1,4", "4", "", []string{"20240129", "20240201", "20240205", "20240208"}},
+		{"20240101", "m -1", "3", "", []string{"20240131", "20240229", "20240331"}},
+		{"20240125", "FREQ=DAILY;COUNT=4", "10", "", []string{"20240127", "20240128"}},
+		{"20240101", "FREQ=MONTHLY;BYDAY=1MO", "2", "", []string{"20240205", "20240304"}},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/occurrences?now=20240126&date=%s&repeat=%s&count=%s&until=%s",
+			v.date, url.QueryEscape(v.repeat), v.count, v.until)
+		body, err := getBody(urlPath)
+		assert.NoError(t, err)
+		var dates []string
+		assert.NoError(t, json.Unmarshal(body, &dates), string(body))
+		assert.Equal(t, v.want, dates, `%v`, v)
+	}
+
+	for _, v := range []string{
+		"api/occurrences?now=20240126&date=20240101",
+		"api/occurrences?now=20240126&date=20240101&repeat=w+9",
+		"api/occurrences?now=20240126&date=20240101&repeat=d+1&count=1000",
+		"api/occurrences?now=20240126&date=20240101&repeat=d+1&until=ooops",
+	} {
+		body, err := getBody(v)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m), string(body))
+		assert.NotEmpty(t, m["error"], v)
+	}
+}
@@ -0,0 +1,488 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// описание API и проверка ответов по нему
+type apiSpec struct {
+	doc map[string]any
+	// операции, ответы которых проверены: "GET /api/task"
+	covered map[string]bool
+}
+
+// функция возвращает узел документа по ссылке вида #/components/schemas/Task
+func (s *apiSpec) resolve(node map[string]any) map[string]any {
+	for node != nil {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var cur any = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, _ := cur.(map[string]any)
+			cur = m[part]
+		}
+		node, _ = cur.(map[string]any)
+	}
+	return nil
+}
+
+// функция ищет в описании операцию для метода и пути запроса
+func (s *apiSpec) operation(method, path string) (string, map[string]any) {
+	paths, _ := s.doc["paths"].(map[string]any)
+	segs := strings.Split(path, "/")
+	for tmpl, item := range paths {
+		tsegs := strings.Split(tmpl, "/")
+		if len(tsegs) != len(segs) {
+			continue
+		}
+		match := true
+		for i := range tsegs {
+			if tsegs[i] != segs[i] && !strings.HasPrefix(tsegs[i], "{") {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		op, _ := item.(map[string]any)[strings.ToLower(method)].(map[string]any)
+		return method + " " + tmpl, op
+	}
+	return "", nil
+}
+
+// функция проверяет значение по схеме; поддерживается то подмножество JSON Schema, которое есть в описании
+func (s *apiSpec) validate(schema map[string]any, v any, at string) []string {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, at+": "+fmt.Sprintf(format, args...))
+	}
+	for _, sub := range asList(schema["allOf"]) {
+		errs = append(errs, s.validate(sub.(map[string]any), v, at)...)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if e == v {
+				found = true
+			}
+		}
+		if !found {
+			fail("%v is not one of %v", v, enum)
+		}
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("want object, got %T", v)
+			break
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for _, name := range asList(schema["required"]) {
+			if _, ok := obj[name.(string)]; !ok {
+				fail("no required property %s", name)
+			}
+		}
+		for name, val := range obj {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					fail("unknown property %s", name)
+				}
+				continue
+			}
+			errs = append(errs, s.validate(prop, val, at+"."+name)...)
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			fail("want array, got %T", v)
+			break
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range arr {
+			errs = append(errs, s.validate(items, item, at+"["+strconv.Itoa(i)+"]")...)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("want string, got %T", v)
+			break
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(str) {
+			fail("%q doesn't match %s", str, pattern)
+		}
+		if min, ok := schema["minLength"].(float64); ok && float64(len(str)) < min {
+			fail("%q is shorter than %v", str, min)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				fail("%q is not date-time", str)
+			}
+		}
+	case "integer", "number":
+		if _, ok := v.(float64); !ok {
+			fail("want number, got %T", v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("want boolean, got %T", v)
+		}
+	}
+	return errs
+}
+
+func asList(v any) []any {
+	list, _ := v.([]any)
+	return list
+}
+
+// функция проверяет ответ по описанию операции и отмечает операцию как проверенную
+func (s *apiSpec) check(t *testing.T, method, path string, resp *http.Response, body []byte) {
+	t.Helper()
+	name, op := s.operation(method, path)
+	if !assert.NotNil(t, op, "%s %s is not described", method, path) {
+		return
+	}
+	s.covered[name] = true
+	responses, _ := op["responses"].(map[string]any)
+	ref, ok := responses[strconv.Itoa(resp.StatusCode)].(map[string]any)
+	if !assert.True(t, ok, "%s: status %d is not described", name, resp.StatusCode) {
+		return
+	}
+	content, _ := s.resolve(ref)["content"].(map[string]any)
+	if len(content) == 0 {
+		assert.Empty(t, body, "%s %d: body must be empty", name, resp.StatusCode)
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]any)
+	if !assert.True(t, ok, "%s %d: content type %q is not described", name, resp.StatusCode, mediaType) {
+		return
+	}
+	schema, _ := media["schema"].(map[string]any)
+	var value any
+	if mediaType == "application/json" {
+		if !assert.NoError(t, json.Unmarshal(body, &value), "%s %d: %s", name, resp.StatusCode, body) {
+			return
+		}
+	} else {
+		value = strings.TrimSuffix(string(body), "\n")
+	}
+	errs := s.validate(schema, value, "body")
+	assert.Empty(t, errs, "%s %d: %s", name, resp.StatusCode, body)
+}
+
+// функция выполняет запрос к серверу, проверяет ответ по описанию и возвращает разобранный джисон
+func (s *apiSpec) call(t *testing.T, srv *httptest.Server, token, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader([]byte(body)))
+	assert.NoError(t, err)
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := srv.Client().Do(req)
+	if !assert.NoError(t, err) {
+		return 0, nil
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	s.check(t, method, req.URL.Path, resp, data)
+	var m map[string]any
+	json.Unmarshal(data, &m)
+	return resp.StatusCode, m
+}
+
+func TestOpenAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{Password: "12345"}, now)
+
+	resp, err := srv.Client().Get(srv.URL + "/api/openapi.json")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	spec := &apiSpec{covered: make(map[string]bool)}
+	if !assert.NoError(t, json.Unmarshal(data, &spec.doc)) {
+		return
+	}
+	assert.Equal(t, "3.0.3", spec.doc["openapi"])
+	spec.check(t, http.MethodGet, "/api/openapi.json", resp, data)
+
+	// вход
+	spec.call(t, srv, "", http.MethodPost, "/api/signin", `{"password": "54321"}`)
+	_, ret := spec.call(t, srv, "", http.MethodPost, "/api/signin", `{"password": "12345"}`)
+	token, _ := ret["token"].(string)
+	assert.NotEmpty(t, token)
+	spec.call(t, srv, "", http.MethodGet, "/api/tasks", "")
+	spec.call(t, srv, "", http.MethodGet, "/api/v2/tasks", "")
+
+	// расчет дат
+	spec.call(t, srv, "", http.MethodGet, "/api/nextdate?now=20240126&date=20240126&repeat=d+1", "")
+	spec.call(t, srv, "", http.MethodGet, "/api/nextdate?now=20240126&date=20240126&repeat=d+1+count=1", "")
+	spec.call(t, srv, "", http.MethodGet, "/api/nextdate?now=20240126&date=20240126&repeat=x", "")
+	spec.call(t, srv, "", http.MethodGet, "/api/occurrences?now=20240126&date=20240126&repeat=d+2&count=3", "")
+	spec.call(t, srv, "", http.MethodGet, "/api/occurrences?now=20240126&date=20240126", "")
+
+	// API фронтенда
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task",
+		`{"date": "20240126", "title": "Полить цветы", "repeat": "d 2 count=5", "time": "09:30", "timezone": "Europe/Moscow"}`)
+	id, _ := ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/task", `{"date": "20240126"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/task?id="+id, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/task?id=999999", "")
+	spec.call(t, srv, token, http.MethodPut, "/api/task",
+		`{"id": "`+id+`", "date": "20240126", "title": "Полить кактус", "repeat": "d 2 count=5"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/task", `{"id": "999999", "title": "Тест"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks?search=кактус", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/skip?id="+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/reschedule?id="+id+"&date=20240210", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/reschedule?id="+id+"&date=завтра", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/done?id="+id, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/task?id="+id, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/task?id="+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/done?id="+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/skip?id="+id, "")
+
+	// API v2
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks", `{"title": "Позвонить"}`)
+	once, _ := ret["id"].(string)
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks",
+		`{"date": "20240126", "title": "Зарядка", "repeat": "d 1", "repeat_mode": "after-completion"}`)
+	id, _ = ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks", `{"title": `)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks", `{"title": "Тест", "repeat": "d 500"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks?limit=1000", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks/"+id, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks/999999", "")
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id, `{"title": "Зарядка утром", "repeat": "d 1"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id, `{"id": "999999", "title": "Тест"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id, `{"title": ""}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id, `[]`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/999999", `{"title": "Тест"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/skip", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/reschedule", `{"date": "20240301"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/reschedule", `{"date": "1 марта"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/reschedule", `{"date": `)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/999999/reschedule", `{"date": "20240301"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+once+"/skip", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+once+"/complete", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+once+"/complete", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+once+"/skip", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+id, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+id, "")
+
+	// метки
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks", `{"title": "Отчет", "tags": ["#Work", "срочно"]}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks", `{"title": "Отчет", "tags": ["два слова"]}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/tags", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks?tag=work", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks?tag=work&tag=срочно", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tags", "")
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tags/work", `{"name": "job"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tags/job", `{"name": "срочно"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tags/job", `{"name": ""}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tags/job", `{"name": `)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tags/work", `{"name": "job"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tags/job/merge", `{"into": "срочно"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tags/job/merge", `{"into": "срочно"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tags/срочно/merge", `{"into": "a,b"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tags/срочно/merge", `[]`)
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tags/срочно", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tags/срочно", "")
+
+	// списки
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/list", `{"name": "Работа", "colour": "#FF8800"}`)
+	list, _ := ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/list", `{"name": " "}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/list?id="+list, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/list?id=999999", "")
+	spec.call(t, srv, token, http.MethodPut, "/api/list", `{"id": "`+list+`", "name": "Работа", "position": 5}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/list", `{"id": "`+list+`", "name": "Работа", "colour": "red"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/lists", "")
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Отчет", "list_id": "`+list+`"}`)
+	id, _ = ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Отчет", "list_id": "999999"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks?list="+list, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/move?id="+id+"&list=1", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/move?id="+id+"&list=999999", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/list?id="+list+"&tasks=cascade", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/list?id=1", "")
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/v2/lists", `{"name": "Покупки"}`)
+	list, _ = ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/lists", `{"name": "Покупки"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/lists", `{"name": ""}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/lists", `{"name": `)
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/lists", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/lists/"+list, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/lists/999999", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks?list="+list, "")
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/lists/"+list, `{"name": "Магазин", "colour": "#00aa00"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/lists/"+list, `{"name": "Магазин", "position": -1}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/lists/"+list, `{"name": `)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/lists/1", `{"name": "Магазин"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/lists/999999", `{"name": "Дом"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/move", `{"list_id": "`+list+`"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/move", `{"list_id": "999999"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/move", `{"list_id": `)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/999999/move", `{"list_id": "1"}`)
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/lists/"+list+"?tasks=archive", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/lists/"+list+"?to=1", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/lists/"+list, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/lists/1", "")
+
+	// чек-листы
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task", `{"date": "20240126", "title": "Уборка", "repeat": "d 7"}`)
+	id, _ = ret["id"].(string)
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task/checklist?id="+id, `{"text": "Пропылесосить"}`)
+	item, _ := ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/task/checklist?id="+id, `{"text": "Ковер", "parent_id": "`+item+`"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/task/checklist?id="+id, `{"text": " "}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/task/checklist?id="+id, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/task/checklist?id=999999", "")
+	spec.call(t, srv, token, http.MethodPut, "/api/task/checklist?id="+id, `{"id": "`+item+`", "done": true}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/task/checklist?id="+id, `{"id": "999999", "done": true}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/task?id="+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/done?id="+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/done?id="+id+"&force=да", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/done?id="+id+"&force=true", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/task/checklist?id="+id+"&item="+item, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/task/checklist?id="+id+"&item="+item, "")
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/checklist", `{"text": "Помыть пол"}`)
+	item, _ = ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/checklist", `{"text": "Тест", "parent_id": "999999"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/checklist", `{"text": `)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/999999/checklist", `{"text": "Тест"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks/"+id+"/checklist", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks/999999/checklist", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks/"+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/complete?force=1", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/complete?force=да", "")
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id+"/checklist/"+item, `{"text": "Помыть пол", "done": true, "position": 3}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id+"/checklist/"+item, `{"id": "999999"}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id+"/checklist/"+item, `{"text": ""}`)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id+"/checklist/"+item, `{"text": `)
+	spec.call(t, srv, token, http.MethodPut, "/api/v2/tasks/"+id+"/checklist/999999", `{"done": true}`)
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+id+"/checklist/"+item, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+id+"/checklist/"+item, "")
+
+	// зависимости
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Купить краску"}`)
+	first, _ := ret["id"].(string)
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Покрасить забор"}`)
+	second, _ := ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/task/dependency?id="+second+"&on="+first, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/dependency?id="+first+"&on="+second, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/dependency?id="+first+"&on=999999", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/task/graph?id="+first, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/task/graph?id=999999", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks?blocked=true", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks?blocked=нет", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/done?id="+second, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/task/dependency?id="+second+"&on="+first, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/task/dependency?id="+second+"&on="+first, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+second+"/dependencies", `{"depends_on": "`+first+`"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+first+"/dependencies", `{"depends_on": "`+second+`"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+first+"/dependencies", `{"depends_on": "999999"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+first+"/dependencies", `{"depends_on": `)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/999999/dependencies", `{"depends_on": "`+first+`"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks/"+second+"/graph", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks/999999/graph", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks?blocked=false", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+second+"/complete", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+second+"/dependencies/"+first, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+second+"/dependencies/"+first, "")
+
+	// приоритет, статус и доска
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Релиз", "priority": "urgent", "status": "in_progress", "estimate": "90"}`)
+	id, _ = ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Релиз", "priority": "важно"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks", `{"title": "Релиз", "status": "later"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/task/status?id="+id+"&status=waiting", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/task/status?id="+id+"&status=later", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks?status=waiting", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/board", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/board?status=waiting&limit=1", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/board?cursor=abc", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/status", `{"status": "done"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/status", `{"status": "later"}`)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/status", `{"status": `)
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/999999/status", `{"status": "done"}`)
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/board?list=1", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/board?status=later", "")
+
+	// история выполнений и архив
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Отправить отчет"}`)
+	id, _ = ret["id"].(string)
+	spec.call(t, srv, token, http.MethodPost, "/api/task/done?id="+id, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/tasks?archived=true", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/tasks?archived=нет", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/history", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/history?from=вчера", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/history/undo", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/history?from=20240101&to=20991231&limit=10", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/history?limit=0", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/history/undo", "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/history/undo", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/history/undo", "")
+
+	// корзина
+	_, ret = spec.call(t, srv, token, http.MethodPost, "/api/task", `{"title": "Черновик"}`)
+	id, _ = ret["id"].(string)
+	spec.call(t, srv, token, http.MethodDelete, "/api/task?id="+id, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/trash", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/trash?limit=0", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/trash/restore?id="+id, "")
+	spec.call(t, srv, token, http.MethodPost, "/api/trash/restore?id="+id, "")
+	spec.call(t, srv, token, http.MethodDelete, "/api/v2/tasks/"+id, "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/trash?limit=10", "")
+	spec.call(t, srv, token, http.MethodGet, "/api/v2/trash?limit=все", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/trash/"+id+"/restore", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/trash/"+id+"/restore", "")
+	spec.call(t, srv, token, http.MethodPost, "/api/v2/trash/abc/restore", "")
+
+	// каждая операция из описания должна быть проверена хотя бы одним запросом
+	paths, _ := spec.doc["paths"].(map[string]any)
+	var missed []string
+	for tmpl, item := range paths {
+		for method := range item.(map[string]any) {
+			if method == "parameters" {
+				continue
+			}
+			if name := strings.ToUpper(method) + " " + tmpl; !spec.covered[name] {
+				missed = append(missed, name)
+			}
+		}
+	}
+	sort.Strings(missed)
+	assert.Empty(t, missed, "operations without checked responses")
+}
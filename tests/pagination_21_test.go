@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// функция проходит по всем страницам списка задач по две задачи и возвращает заголовки задач и их общее количество
+func walkTitles(t *testing.T, list func(query string) map[string]any, query string) ([]string, float64) {
+	var titles []string
+	var total float64
+	cursor := ""
+	for i := 0; i < 100; i++ {
+		q := query + "&limit=2"
+		if cursor != "" {
+			q += "&cursor=" + url.QueryEscape(cursor)
+		}
+		page := list(q)
+		if !assert.Nil(t, page["error"], query) {
+			break
+		}
+		tasks, _ := page["tasks"].([]any)
+		for _, task := range tasks {
+			title, _ := task.(map[string]any)["title"].(string)
+			titles = append(titles, title)
+		}
+		total, _ = page["total"].(float64)
+		cursor, _ = page["next_cursor"].(string)
+		if cursor == "" {
+			break
+		}
+	}
+	return titles, total
+}
+
+// проверки списка, общие для всех хранилищ; первая задача - с самой ранней датой
+func checkPagination(t *testing.T, list func(query string) map[string]any) {
+	for _, v := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Яблоко", "Банан", "Вишня", "Арбуз", "Груша"}},
+		{"sort=date&order=desc", []string{"Груша", "Арбуз", "Вишня", "Банан", "Яблоко"}},
+		{"sort=title", []string{"Арбуз", "Банан", "Вишня", "Груша", "Яблоко"}},
+		{"sort=title&order=desc", []string{"Яблоко", "Груша", "Вишня", "Банан", "Арбуз"}},
+		{"sort=id&order=desc", []string{"Груша", "Арбуз", "Вишня", "Банан", "Яблоко"}},
+		{"from=20990101&to=20990102", []string{"Банан", "Вишня", "Арбуз"}},
+		{"has_repeat=true", []string{"Банан", "Арбуз"}},
+		{"has_repeat=false&sort=title", []string{"Вишня", "Груша", "Яблоко"}},
+		{"search=ба+OR+ви&sort=title", []string{"Банан", "Вишня"}},
+		{"search=02.01.2099", []string{"Арбуз"}},
+	} {
+		titles, total := walkTitles(t, list, v.query)
+		assert.Equal(t, v.want, titles, v.query)
+		assert.Equal(t, float64(len(v.want)), total, v.query)
+	}
+
+	// курсор действует только для того порядка, для которого выдан
+	page := list("sort=title&limit=1")
+	cursor, _ := page["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+	for _, query := range []string{
+		"sort=id&cursor=" + url.QueryEscape(cursor),
+		"sort=title&order=desc&cursor=" + url.QueryEscape(cursor),
+		"cursor=abc",
+		"sort=priority",
+		"order=up",
+		"limit=0",
+		"from=2099",
+		"has_repeat=yes",
+	} {
+		page := list(query)
+		assert.NotNil(t, page["error"], query)
+	}
+}
+
+func TestTasksPagination(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+	for _, v := range []struct {
+		date, title, repeat, time, created string
+	}{
+		{"20200101", "Яблоко", "", "", "2024-01-05T00:00:00Z"},
+		{"20990101", "Банан", "d 1", "", "2024-01-03T00:00:00Z"},
+		{"20990101", "Вишня", "", "10:00", "2024-01-01T00:00:00Z"},
+		{"20990102", "Арбуз", "w 1", "", "2024-01-04T00:00:00Z"},
+		{"20990103", "Груша", "", "", "2024-01-02T00:00:00Z"},
+	} {
+		_, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat, due_time, created_at)
+		VALUES (?, ?, '', ?, ?, ?)`, v.date, v.title, v.repeat, v.time, v.created)
+		assert.NoError(t, err)
+	}
+
+	list := func(query string) map[string]any {
+		ret, err := postJSON("api/tasks?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		return ret
+	}
+	checkPagination(t, list)
+
+	for _, v := range []struct {
+		query string
+		want  []string
+	}{
+		{"sort=created", []string{"Вишня", "Груша", "Банан", "Арбуз", "Яблоко"}},
+		{"sort=created&order=desc", []string{"Яблоко", "Арбуз", "Банан", "Груша", "Вишня"}},
+		{"overdue=true", []string{"Яблоко"}},
+		{"overdue=false&sort=title", []string{"Арбуз", "Банан", "Вишня", "Груша"}},
+	} {
+		titles, total := walkTitles(t, list, v.query)
+		assert.Equal(t, v.want, titles, v.query)
+		assert.Equal(t, float64(len(v.want)), total, v.query)
+	}
+
+	// больше 66 задач тоже видно
+	for i := 0; i < 70; i++ {
+		_, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20990201', 'Задача', '', '')`)
+		assert.NoError(t, err)
+	}
+	titles, total := walkTitles(t, list, "sort=id")
+	assert.Len(t, titles, 75)
+	assert.Equal(t, float64(75), total)
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
+
+func TestTasksPaginationMemory(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+	for _, v := range []struct {
+		date, title, repeat, time string
+	}{
+		{"20240126", "Яблоко", "", ""},
+		{"20990101", "Банан", "d 1", ""},
+		{"20990101", "Вишня", "", "10:00"},
+		{"20990102", "Арбуз", "w 1", ""},
+		{"20990103", "Груша", "", ""},
+	} {
+		code, _ := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{
+			"date": v.date, "title": v.title, "repeat": v.repeat, "time": v.time,
+		})
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	list := func(query string) map[string]any {
+		_, ret := callJSON(t, srv, http.MethodGet, "/api/tasks?"+query, nil)
+		return ret
+	}
+	checkPagination(t, list)
+
+	// API v2 понимает те же параметры, а ошибки в них - 422
+	_, ret := callJSON(t, srv, http.MethodGet, "/api/v2/tasks?sort=title&limit=1", nil)
+	assert.Equal(t, float64(5), ret["total"])
+	assert.NotEmpty(t, ret["next_cursor"])
+	code, ret := callJSON(t, srv, http.MethodGet, "/api/v2/tasks?cursor=abc", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, map[string]any{"field": "cursor"}, ret["details"])
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// задачи для поиска с условиями на поля, идут по дате
+var queryTasks = []struct {
+	date, title, comment, repeat string
+}{
+	{"20250301", "Отчет за март", "черновик", ""},
+	{"20250305", "Отчет недельный", "", "w 1"},
+	{"20250310", "Отчет квартальный", "отправить: http://example.com", ""},
+	{"20250312", "Позвонить", "про отчет", "d 7"},
+	{"20250315", "Отчет годовой", "", "FREQ=YEARLY"},
+}
+
+// проверки условий строки поиска, общие для всех хранилищ
+func checkSearchQuery(t *testing.T, list func(query string) map[string]any) {
+	for _, v := range []struct {
+		search string
+		want   []string
+	}{
+		{"title:отчет repeat:none date:>=01.03.2025 date:<15.03.2025 -comment:черновик", []string{"Отчет квартальный"}},
+		{"title:отчет", []string{"Отчет за март", "Отчет недельный", "Отчет квартальный", "Отчет годовой"}},
+		{"comment:отчет", []string{"Позвонить"}},
+		{`comment:"про отчет"`, []string{"Позвонить"}},
+		{"отчет -title:отчет", []string{"Позвонить"}},
+		{"repeat:any", []string{"Отчет недельный", "Позвонить", "Отчет годовой"}},
+		{"-repeat:none", []string{"Отчет недельный", "Позвонить", "Отчет годовой"}},
+		{"repeat:w", []string{"Отчет недельный"}},
+		{"repeat:d", []string{"Позвонить"}},
+		{"repeat:rrule", []string{"Отчет годовой"}},
+		{"repeat:any -недельный", []string{"Позвонить", "Отчет годовой"}},
+		{"date:>10.03.2025", []string{"Позвонить", "Отчет годовой"}},
+		{"date:<=05.03.2025", []string{"Отчет за март", "Отчет недельный"}},
+		{"-date:=05.03.2025 repeat:w", nil},
+		{"05.03.2025", []string{"Отчет недельный"}},
+		{"отчет 10.03.2025", []string{"Отчет квартальный"}},
+		{"квартальный OR годовой date:<12.03.2025", []string{"Отчет квартальный"}},
+		// слово с двоеточием, которое не является полем, ищется как обычное слово
+		{"http://example.com", []string{"Отчет квартальный"}},
+		{"Отправить: отчет", []string{"Отчет квартальный"}},
+		{"colour:red", nil},
+	} {
+		titles, total := walkTitles(t, list, "sort=date&search="+url.QueryEscape(v.search))
+		assert.Equal(t, v.want, titles, v.search)
+		assert.Equal(t, float64(len(v.want)), total, v.search)
+	}
+
+	// слова из условий с полем отмечаются только в своем поле
+	tasks := searchPage(list, "comment:отчет")
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "Позвонить", tasks[0]["title_highlight"])
+		assert.Equal(t, "про <mark>отчет</mark>", tasks[0]["snippet"])
+	}
+	// без слов отметок нет
+	tasks = searchPage(list, "repeat:w")
+	if assert.Len(t, tasks, 1) {
+		assert.Nil(t, tasks[0]["title_highlight"])
+	}
+
+	// в ошибке указано неверное условие и его позиция
+	for _, v := range []struct {
+		search, clause string
+	}{
+		{"title:отчет tag:", `"tag:" at position 13`},
+		{"date:>=32.03.2025", `"date:>=32.03.2025" at position 1`},
+		{"отчет repeat:weekly", `"repeat:weekly" at position 7`},
+		{"title:", `"title:" at position 1`},
+		{`отчет comment:"черно`, `"\"черно" at position 15`},
+	} {
+		page := list("search=" + url.QueryEscape(v.search))
+		assert.Contains(t, page["error"], v.clause, v.search)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+	for _, v := range queryTasks {
+		_, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`,
+			v.date, v.title, v.comment, v.repeat)
+		assert.NoError(t, err)
+	}
+
+	list := func(query string) map[string]any {
+		ret, err := postJSON("api/tasks?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		return ret
+	}
+	checkSearchQuery(t, list)
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
+
+func TestSearchQueryMemory(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+	for _, v := range queryTasks {
+		code, _ := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{
+			"date": v.date, "title": v.title, "comment": v.comment, "repeat": v.repeat,
+		})
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	list := func(query string) map[string]any {
+		_, ret := callJSON(t, srv, http.MethodGet, "/api/tasks?"+query, nil)
+		return ret
+	}
+	checkSearchQuery(t, list)
+
+	// API v2 отдает неверное условие в details
+	code, ret := callJSON(t, srv, http.MethodGet, "/api/v2/tasks?search="+url.QueryEscape("отчет repeat:weekly"), nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, map[string]any{"field": "search", "token": "repeat:weekly", "position": float64(7)}, ret["details"])
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateRRule(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "FREQ=HOURLY", ""},
+		{"20240101", "FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"20240101", "FREQ=DAILY;COUNT=2;UNTIL=20240301", ""},
+		{"20240101", "FREQ=DAILY;INTERVAL=0", ""},
+		{"20240101", "FREQ=YEARLY;INTERVAL=1000000000", ""},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"20240101", "FREQ=DAILY;INTERVAL=10", "20240131"},
+		{"20240101", "RRULE:FREQ=YEARLY", "20250101"},
+		{"20200229", "FREQ=YEARLY", "20240229"},
+		{"20240126", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "20240205"},
+		{"20240126", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", "20240128"},
+		{"20240126", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU;WKST=SU", "20240204"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=31", "20240131"},
+		{"20240201", "FREQ=MONTHLY;BYMONTHDAY=-1", "20240229"},
+		{"20230101", "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=8", "20240308"},
+		{"20240120", "FREQ=DAILY;COUNT=3", ""},
+		{"20240125", "FREQ=DAILY;COUNT=3", "20240127"},
+		{"20240101", "FREQ=WEEKLY;UNTIL=20240128", ""},
+		{"20240101", "FREQ=WEEKLY;UNTIL=20240205", "20240129"},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+func TestDoneRRule(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Проверить правило RRULE",
+		repeat: "FREQ=DAILY;INTERVAL=2",
+	})
+
+	for i := 0; i < 2; i++ {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var task Task
+		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		now = now.AddDate(0, 0, 2)
+		assert.Equal(t, task.Date, now.Format(`20060102`))
+	}
+
+	id = addTask(t, task{
+		title:  "Последний раз",
+		repeat: "FREQ=DAILY;COUNT=1",
+	})
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/nextdate"
+	"github.com/stretchr/testify/assert"
+)
+
+type ruleError struct {
+	repeat string
+	want   string
+}
+
+func TestRepeatError(t *testing.T) {
+	tbl := []ruleError{
+		{"k 34", `"k" at position 1`},
+		{"d", `at position 2`},
+		{"d 0", `"0" at position 3`},
+		{"w 1,9", `"9" at position 5`},
+		{"m 1 13", `"13" at position 5`},
+		{"y 5", `"5" at position 3`},
+		{"FREQ=WEEKLY;BYDAY=MO,XX", `"XX" at position 22`},
+		{"FREQ=DAILY;COUNT=0", `"0" at position 18`},
+		{"FREQ=SOMETIMES", `"SOMETIMES" at position 6`},
+		{"FREQ=WEEKLY;INTERVAL=101", `interval must be from 1 to 100: "101" at position 22`},
+		{"FREQ=DAILY;INTERVAL=100000000000", `interval must be from 1 to 400: "100000000000" at position 21`},
+	}
+	// правило проверяется, даже если дата задачи в будущем
+	date := time.Now().AddDate(0, 0, 5).Format(`20060102`)
+	for _, v := range tbl {
+		m, err := postJSON("api/task", map[string]any{
+			"date":   date,
+			"title":  "Неправильное правило",
+			"repeat": v.repeat,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+
+		e, ok := m["error"]
+		assert.True(t, ok, "Ожидается ошибка для правила %q", v.repeat)
+		assert.Contains(t, fmt.Sprint(e), v.want)
+	}
+}
+
+func TestRuleRoundTrip(t *testing.T) {
+	tbl := []string{
+		"d 1",
+		"d 400",
+		"bd 3",
+		"w 1,3,7",
+		"w 2 /3",
+		"m 1,-1",
+		"m 15 1,6",
+		"m 10 /2",
+		"mw 1:1,-1:5",
+		"mw 2:3 3,9 /2",
+		"mb 1,-1",
+		"mb 5 12",
+		"y",
+		"y /4",
+		"d 7 count=5",
+		"w 5 until=20241231",
+		"bd 1 shift=prev",
+		"m 31 shift=next",
+		"mw -1:5 /3 count=4 shift=prev",
+		"FREQ=DAILY",
+		"RRULE:FREQ=DAILY;INTERVAL=10;COUNT=3",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;WKST=SU",
+		"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+		"FREQ=MONTHLY;BYMONTHDAY=-1;UNTIL=20241231",
+		"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=8;UNTIL=20241231T120000Z",
+		"FREQ=MONTHLY;BYDAY=2TU;X-SHIFT=NEXT",
+	}
+	for _, repeat := range tbl {
+		r, err := nextdate.Parse(repeat)
+		if !assert.NoError(t, err, repeat) {
+			continue
+		}
+		again, err := nextdate.Parse(r.String())
+		if assert.NoError(t, err, "%q -> %q", repeat, r.String()) {
+			assert.Equal(t, r, again, "%q -> %q", repeat, r.String())
+			assert.Equal(t, r.String(), again.String(), repeat)
+		}
+	}
+}
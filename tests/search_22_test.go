@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// задачи для поиска, идут по дате
+var searchTasks = []struct {
+	date, title, comment string
+}{
+	{"20990101", "Отчет за квартал", "отправить в бухгалтерию"},
+	{"20990102", "Позвонить маме", "спросить про квартальный отчет и не забыть поздравить с днем рождения тетю Валю"},
+	{"20990103", "Купить молоко", "и хлеб"},
+	{"20990104", "Черновик отчета", "draft"},
+	{"20990105", "Счет & акт", ""},
+}
+
+// функция возвращает первую страницу списка найденных задач
+func searchPage(list func(query string) map[string]any, search string) []map[string]any {
+	page := list("sort=date&search=" + url.QueryEscape(search))
+	tasks, _ := page["tasks"].([]any)
+	ret := make([]map[string]any, 0, len(tasks))
+	for _, task := range tasks {
+		ret = append(ret, task.(map[string]any))
+	}
+	return ret
+}
+
+// проверки поиска по словам, общие для всех хранилищ
+func checkSearch(t *testing.T, list func(query string) map[string]any) {
+	for _, v := range []struct {
+		search string
+		want   []string
+	}{
+		{"ОТЧЕТ", []string{"Отчет за квартал", "Позвонить маме", "Черновик отчета"}},
+		{"квартал", []string{"Отчет за квартал", "Позвонить маме"}},
+		{`"квартал"`, []string{"Отчет за квартал"}},
+		{`"квартальный отчет"`, []string{"Позвонить маме"}},
+		{"молоко OR маме", []string{"Позвонить маме", "Купить молоко"}},
+		{"купить хлеб", []string{"Купить молоко"}},
+		{"отчет -черновик", []string{"Отчет за квартал", "Позвонить маме"}},
+		{"отчет NOT draft", []string{"Отчет за квартал", "Позвонить маме"}},
+		{"бассейн", nil},
+	} {
+		titles, total := walkTitles(t, list, "sort=date&search="+url.QueryEscape(v.search))
+		assert.Equal(t, v.want, titles, v.search)
+		assert.Equal(t, float64(len(v.want)), total, v.search)
+	}
+
+	// найденные слова отмечены в заголовке и во фрагменте комментария
+	tasks := searchPage(list, "квартал")
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "Отчет за <mark>квартал</mark>", tasks[0]["title_highlight"])
+		assert.Nil(t, tasks[0]["snippet"])
+		assert.Equal(t, "Позвонить маме", tasks[1]["title_highlight"])
+		assert.Contains(t, tasks[1]["snippet"], "спросить про <mark>квартальный</mark> отчет")
+	}
+	tasks = searchPage(list, "акт")
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "Счет &amp; <mark>акт</mark>", tasks[0]["title_highlight"])
+	}
+	// без поиска по словам отметок нет
+	tasks = searchPage(list, "01.01.2099")
+	if assert.Len(t, tasks, 1) {
+		assert.Nil(t, tasks[0]["title_highlight"])
+	}
+
+	for _, search := range []string{"-отчет", `"отчет`, "!!!"} {
+		page := list("search=" + url.QueryEscape(search))
+		assert.NotNil(t, page["error"], search)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+	for _, v := range searchTasks {
+		_, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, '')`,
+			v.date, v.title, v.comment)
+		assert.NoError(t, err)
+	}
+
+	list := func(query string) map[string]any {
+		ret, err := postJSON("api/tasks?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		return ret
+	}
+	checkSearch(t, list)
+
+	// по умолчанию найденные задачи идут по релевантности: слово в заголовке важнее слова в комментарии
+	titles, _ := walkTitles(t, list, "search=отчет")
+	if assert.Len(t, titles, 3) {
+		assert.Equal(t, "Позвонить маме", titles[2])
+	}
+	titles, _ = walkTitles(t, list, "search=отчет&sort=rank&order=desc")
+	if assert.Len(t, titles, 3) {
+		assert.Equal(t, "Позвонить маме", titles[0])
+	}
+
+	// индекс меняется вместе с таблицей scheduler
+	_, err = db.Exec("UPDATE scheduler SET title='Купить кефир' WHERE title='Купить молоко'")
+	assert.NoError(t, err)
+	titles, _ = walkTitles(t, list, "search=кефир")
+	assert.Equal(t, []string{"Купить кефир"}, titles)
+	titles, _ = walkTitles(t, list, "search=молоко")
+	assert.Empty(t, titles)
+	_, err = db.Exec("DELETE FROM scheduler WHERE title='Купить кефир'")
+	assert.NoError(t, err)
+	titles, _ = walkTitles(t, list, "search=купить")
+	assert.Empty(t, titles)
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
+
+func TestSearchMemory(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+	for _, v := range searchTasks {
+		code, _ := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{
+			"date": v.date, "title": v.title, "comment": v.comment,
+		})
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	list := func(query string) map[string]any {
+		_, ret := callJSON(t, srv, http.MethodGet, "/api/tasks?"+query, nil)
+		return ret
+	}
+	checkSearch(t, list)
+
+	code, ret := callJSON(t, srv, http.MethodGet, "/api/v2/tasks?search=-"+url.QueryEscape("отчет"), nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, map[string]any{"field": "search", "token": "-отчет", "position": float64(1)}, ret["details"])
+}
+
+func TestSearchMigration(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "scheduler.db")
+	store, err := db.OpenSQLite(dbFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	assert.NoError(t, store.Migrate())
+
+	// база без полнотекстового индекса (до миграции 7), в которой уже есть задачи;
+	// задачи пишутся запросом, потому что хранилище работает только с последней схемой
+	version, err := store.SchemaVersion()
+	assert.NoError(t, err)
+	assert.NoError(t, store.Rollback(version-6))
+	old, err := sqlx.Connect("sqlite", dbFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, v := range searchTasks {
+		_, err := old.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, '')`,
+			v.date, v.title, v.comment)
+		assert.NoError(t, err)
+	}
+	old.Close()
+	// при миграции индекс строится по существующим задачам
+	assert.NoError(t, store.Migrate())
+	assert.Len(t, tagTitles(t, store, db.TaskQuery{Search: "отчет"}), 3)
+}
@@ -0,0 +1,269 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// функция возвращает заголовки задач с меткой или строкой поиска
+func tagTitles(t *testing.T, store db.TaskStore, q db.TaskQuery) []string {
+	q.Limit = 10
+	page, err := store.ListTasks(q)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	var titles []string
+	for _, task := range page.Tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+// проверки меток, общие для всех хранилищ
+func checkTags(t *testing.T, store db.TaskStore) {
+	ids := make(map[string]string)
+	for _, v := range []struct {
+		date, title string
+		tags        []string
+	}{
+		{"20240126", "Отчет", []string{"home", "work"}},
+		{"20240127", "Созвон", []string{"work"}},
+		{"20240128", "Прогулка", nil},
+		{"20240129", "Релиз", []string{"urgent", "work"}},
+	} {
+		id, err := store.AddTask(&db.Task{Date: v.date, Title: v.title, Tags: v.tags})
+		assert.NoError(t, err)
+		ids[v.title] = strconv.FormatInt(id, 10)
+	}
+	task, err := store.GetTask(ids["Отчет"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"home", "work"}, task.Tags)
+	}
+	tags, err := store.Tags()
+	assert.NoError(t, err)
+	assert.Equal(t, []*db.Tag{{Name: "work", Count: 3}, {Name: "home", Count: 1}, {Name: "urgent", Count: 1}}, tags)
+
+	// фильтр по меткам и условия с метками в строке поиска
+	assert.Equal(t, []string{"Отчет", "Созвон", "Релиз"}, tagTitles(t, store, db.TaskQuery{Tags: []string{"work"}}))
+	assert.Equal(t, []string{"Релиз"}, tagTitles(t, store, db.TaskQuery{Tags: []string{"work", "urgent"}}))
+	assert.Equal(t, []string{"Отчет"}, tagTitles(t, store, db.TaskQuery{Search: "#home"}))
+	assert.Equal(t, []string{"Отчет", "Созвон"}, tagTitles(t, store, db.TaskQuery{Search: "tag:work -tag:urgent"}))
+	assert.Equal(t, []string{"Созвон"}, tagTitles(t, store, db.TaskQuery{Search: "созвон #WORK"}))
+	assert.Empty(t, tagTitles(t, store, db.TaskQuery{Tags: []string{"nope"}}))
+
+	// слово без поля ищется и в именах меток: как начало имени, а фраза в кавычках - целиком
+	assert.Equal(t, []string{"Отчет", "Созвон", "Релиз"}, tagTitles(t, store, db.TaskQuery{Search: "work"}))
+	assert.Equal(t, []string{"Релиз"}, tagTitles(t, store, db.TaskQuery{Search: "urg"}))
+	assert.Empty(t, tagTitles(t, store, db.TaskQuery{Search: `"urg"`}))
+	assert.Equal(t, []string{"Отчет"}, tagTitles(t, store, db.TaskQuery{Search: "отчет work"}))
+	assert.Equal(t, []string{"Созвон"}, tagTitles(t, store, db.TaskQuery{Search: "work -home -urgent"}))
+	assert.Empty(t, tagTitles(t, store, db.TaskQuery{Search: "title:work"}))
+	assert.ElementsMatch(t, []string{"Отчет", "Прогулка"}, tagTitles(t, store, db.TaskQuery{Search: "прогулка OR home"}))
+
+	// изменение задачи заменяет ее метки
+	task, err = store.GetTask(ids["Созвон"])
+	if assert.NoError(t, err) {
+		task.Tags = []string{"home"}
+		assert.NoError(t, store.UpdTask(task))
+	}
+	tags, err = store.Tags()
+	assert.NoError(t, err)
+	assert.Equal(t, []*db.Tag{{Name: "home", Count: 2}, {Name: "work", Count: 2}, {Name: "urgent", Count: 1}}, tags)
+
+	// переименование
+	tag, err := store.RenameTag("home", "дом")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &db.Tag{Name: "дом", Count: 2}, tag)
+	}
+	tag, err = store.RenameTag("дом", "дом")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &db.Tag{Name: "дом", Count: 2}, tag)
+	}
+	_, err = store.RenameTag("дом", "work")
+	assert.ErrorIs(t, err, db.ErrTagExists)
+	_, err = store.RenameTag("home", "дача")
+	assert.ErrorIs(t, err, db.ErrTagNotFound)
+	assert.Equal(t, []string{"Отчет", "Созвон"}, tagTitles(t, store, db.TaskQuery{Tags: []string{"дом"}}))
+
+	// объединение: у задачи с обеими метками остается одна
+	tag, err = store.MergeTag("urgent", "work")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &db.Tag{Name: "work", Count: 2}, tag)
+	}
+	_, err = store.MergeTag("дом", "дом")
+	assert.NoError(t, err)
+	_, err = store.MergeTag("urgent", "work")
+	assert.ErrorIs(t, err, db.ErrTagNotFound)
+	task, err = store.GetTask(ids["Релиз"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"work"}, task.Tags)
+	}
+	_, err = store.MergeTag("work", "дом")
+	assert.NoError(t, err)
+	task, err = store.GetTask(ids["Отчет"])
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"дом"}, task.Tags)
+	}
+	tags, err = store.Tags()
+	assert.NoError(t, err)
+	assert.Equal(t, []*db.Tag{{Name: "дом", Count: 3}}, tags)
+
+	// удаление метки и задачи
+	assert.NoError(t, store.DelTask(ids["Релиз"]))
+	tags, err = store.Tags()
+	assert.NoError(t, err)
+	assert.Equal(t, []*db.Tag{{Name: "дом", Count: 2}}, tags)
+	assert.NoError(t, store.DelTag("дом"))
+	assert.ErrorIs(t, store.DelTag("дом"), db.ErrTagNotFound)
+	task, err = store.GetTask(ids["Отчет"])
+	if assert.NoError(t, err) {
+		assert.Empty(t, task.Tags)
+	}
+	tags, err = store.Tags()
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+
+	// метку, которая есть только у задачи в корзине, тоже можно переименовать, в облаке ее нет
+	task, err = store.GetTask(ids["Отчет"])
+	if assert.NoError(t, err) {
+		task.Tags = []string{"старое"}
+		assert.NoError(t, store.UpdTask(task))
+	}
+	assert.NoError(t, store.TrashTask(ids["Отчет"], "2024-01-26T12:00:00Z"))
+	tag, err = store.RenameTag("старое", "архив")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &db.Tag{Name: "архив", Count: 0}, tag)
+	}
+}
+
+// функция запускает проверки check на каждом хранилище: SQLite в новом файле и в памяти
+func eachStore(t *testing.T, check func(t *testing.T, store db.TaskStore)) {
+	t.Run("sqlite", func(t *testing.T) {
+		store, err := db.OpenSQLite(filepath.Join(t.TempDir(), "scheduler.db"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer store.Close()
+		assert.NoError(t, store.Migrate())
+		check(t, store)
+	})
+	t.Run("memory", func(t *testing.T) {
+		check(t, db.NewMemory())
+	})
+}
+
+func TestTagsStore(t *testing.T) {
+	eachStore(t, checkTags)
+}
+
+func TestTagsAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	// метки приводятся к нижнему регистру без #, повторы убираются
+	code, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{
+		"date": "20240126", "title": "Отчет", "tags": []string{"#Work", "work", "Дом"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []any{"work", "дом"}, ret["tags"])
+	id, _ := ret["id"].(string)
+	for _, tags := range [][]string{{""}, {"два слова"}, {"a,b"}} {
+		code, ret = callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"title": "Тест", "tags": tags})
+		assert.Equal(t, http.StatusBadRequest, code, tags)
+		assert.NotEmpty(t, ret["error"], tags)
+	}
+
+	// без поля tags метки остаются прежними, пустой список их снимает
+	code, _ = callJSON(t, srv, http.MethodPut, "/api/task", map[string]any{"id": id, "date": "20240126", "title": "Отчет за год"})
+	assert.Equal(t, http.StatusOK, code)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, []any{"work", "дом"}, ret["tags"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tags", nil)
+	assert.Equal(t, []any{
+		map[string]any{"name": "work", "count": float64(1)},
+		map[string]any{"name": "дом", "count": float64(1)},
+	}, ret["tags"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?tag=%23WORK&tag="+url.QueryEscape("дом"), nil)
+	assert.Equal(t, float64(1), ret["total"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks?tag=a,b", nil)
+	assert.NotEmpty(t, ret["error"])
+
+	// переименование и объединение в API v2
+	resp, ret := callV2(t, srv, http.MethodPut, "/api/v2/tags/work", `{"name": "Job"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{"name": "job", "count": float64(1)}, ret)
+	resp, ret = callV2(t, srv, http.MethodPut, "/api/v2/tags/job", `{"name": "job"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{"name": "job", "count": float64(1)}, ret)
+	resp, ret = callV2(t, srv, http.MethodPut, "/api/v2/tags/job", `{"name": "дом"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "conflict", ret["code"])
+	resp, ret = callV2(t, srv, http.MethodPut, "/api/v2/tags/job", `{"name": "a b"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string]any{"field": "name"}, ret["details"])
+	resp, _ = callV2(t, srv, http.MethodPut, "/api/v2/tags/work", `{"name": "job"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tags/job/merge", `{"into": "#Дом"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{"name": "дом", "count": float64(1)}, ret)
+	resp, _ = callV2(t, srv, http.MethodDelete, "/api/v2/tags/дом", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tags", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []any{}, ret["tags"])
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Nil(t, ret["tags"])
+
+	// метка задачи из архива переименовывается, а в ответе у нее нет задач
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Архив", "tags": []string{"old"}})
+	assert.Equal(t, http.StatusOK, code)
+	archived, _ := ret["id"].(string)
+	code, _ = callJSON(t, srv, http.MethodPost, "/api/task/done?id="+archived, nil)
+	assert.Equal(t, http.StatusOK, code)
+	resp, ret = callV2(t, srv, http.MethodPut, "/api/v2/tags/old", `{"name": "прошлое"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{"name": "прошлое", "count": float64(0)}, ret)
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tags/прошлое/merge", `{"into": "old"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{"name": "old", "count": float64(0)}, ret)
+}
+
+func TestTags(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date": time.Now().Format(`20060102`), "title": "Отчет", "tags": []string{"#Work"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	assert.Equal(t, []any{"work"}, ret["tags"])
+
+	ret, err = postJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"name": "work", "count": float64(1)}}, ret["tags"])
+	ret, err = postJSON("api/tasks?tag=work", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), ret["total"])
+	ret, err = postJSON("api/tasks?search="+url.QueryEscape("#work"), nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), ret["total"])
+
+	// метки задачи из корзины не видны в облаке, но остаются до окончательного удаления
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	ret, err = postJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{}, ret["tags"])
+	var num int
+	assert.NoError(t, db.Get(&num, "SELECT count(*) FROM tags"))
+	assert.Equal(t, 1, num)
+}
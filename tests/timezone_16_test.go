@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateTimeZone(t *testing.T) {
+	tbl := []struct {
+		now  string
+		tz   string
+		want string
+	}{
+		{"2024-01-26T23:30:00Z", "", "20240127"},
+		{"2024-01-26T23:30:00Z", "Europe/Moscow", "20240128"},
+		{"2024-01-27T02:30:00+03:00", "", "20240128"},
+		{"2024-01-27T02:30:00+03:00", "America/New_York", "20240127"},
+		{"20240126", "Asia/Tokyo", "20240127"},
+		{"2024-01-26", "", ""},
+		{"20240126", "Mars/Olympus", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=%s&tz=%s&date=20240120&repeat=d+1",
+			url.QueryEscape(v.now), url.QueryEscape(v.tz))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`, v.now, v.tz, v.want)
+	}
+}
+
+func TestTaskTimeZone(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	for _, v := range []map[string]any{
+		{"date": "20300330", "title": "Созвон", "time": "25:00"},
+		{"date": "20300330", "title": "Созвон", "time": "10:00", "timezone": "Mars/Olympus"},
+	} {
+		ret, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v)
+	}
+
+	// в ночь на 31 марта 2030 года в Берлине переходят на летнее время, 02:30 не существует
+	for date, due := range map[string]string{
+		"20300330": "2030-03-30T02:30:00+01:00",
+		"20300331": "2030-03-31T03:30:00+02:00",
+		"20300401": "2030-04-01T02:30:00+02:00",
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":     date,
+			"title":    "Ночной бэкап",
+			"time":     "02:30",
+			"timezone": "Europe/Berlin",
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+
+		ret, err = getTask(id)
+		assert.NoError(t, err)
+		assert.Equal(t, "02:30", ret["time"])
+		assert.Equal(t, "Europe/Berlin", ret["timezone"])
+		assert.Equal(t, due, ret["due"])
+	}
+
+	// при изменении без времени и пояса они остаются прежними
+	id := addTask(t, task{date: "20300330", title: "Созвон"})
+	_, err := postJSON("api/task", map[string]any{
+		"id":       id,
+		"date":     "20300330",
+		"title":    "Созвон",
+		"time":     "16:00",
+		"timezone": "Asia/Tokyo",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	_, err = postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  "20300331",
+		"title": "Созвон",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "16:00", task.DueTime)
+	assert.Equal(t, "Asia/Tokyo", task.TimeZone)
+}
@@ -0,0 +1,249 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/mrScorpio/finalTask/internal/db"
+	"github.com/mrScorpio/finalTask/internal/handlers"
+	"github.com/mrScorpio/finalTask/internal/nextdate"
+	"github.com/mrScorpio/finalTask/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// проверки корзины, общие для всех хранилищ
+func checkTrash(t *testing.T, store db.TaskStore) {
+	ids := make(map[string]int)
+	for _, task := range []db.Task{
+		{Date: "20240126", Title: "Купить краску", Tags: []string{"дом"}},
+		{Date: "20240127", Title: "Покрасить забор"},
+		{Date: "20240128", Title: "Черновик"},
+	} {
+		id, err := store.AddTask(&task)
+		assert.NoError(t, err)
+		ids[task.Title] = int(id)
+	}
+	paint, fence, draft := strconv.Itoa(ids["Купить краску"]), strconv.Itoa(ids["Покрасить забор"]), strconv.Itoa(ids["Черновик"])
+	assert.NoError(t, store.AddDependency(ids["Покрасить забор"], ids["Купить краску"]))
+	assert.NoError(t, store.AdvanceTask(&db.Advance{TaskId: ids["Купить краску"], Date: "20240126",
+		Completion: &db.Completion{TaskId: ids["Купить краску"], Date: "20240125", Origin: "20240125"}}))
+
+	assert.NoError(t, store.TrashTask(paint, "2024-01-26T10:00:00Z"))
+	assert.NoError(t, store.TrashTask(draft, "2024-01-26T11:00:00Z"))
+	assert.ErrorIs(t, store.TrashTask(draft, "2024-01-26T12:00:00Z"), db.ErrNotFound)
+
+	// задача из корзины не видна нигде и не меняется
+	_, err := store.GetTask(paint)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.Equal(t, []string{"Покрасить забор"}, tagTitles(t, store, db.TaskQuery{}))
+	assert.Empty(t, tagTitles(t, store, db.TaskQuery{Archived: true}))
+	assert.Empty(t, tagTitles(t, store, db.TaskQuery{Search: "краску"}))
+	tags, err := store.Tags()
+	if assert.NoError(t, err) {
+		assert.Empty(t, tags)
+	}
+	list, err := store.GetList(db.DefaultList)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, list.Count)
+	}
+	completions, err := store.Completions("", "", 10)
+	if assert.NoError(t, err) {
+		assert.Empty(t, completions)
+	}
+	task := &db.Task{Id: ids["Купить краску"], Date: "20240126", Title: "Купить краску"}
+	assert.ErrorIs(t, store.UpdTask(task), db.ErrNotFound)
+	assert.ErrorIs(t, store.UpDateTask("20240201", 0, paint), db.ErrNotFound)
+	assert.ErrorIs(t, store.SetStatus(paint, db.StatusDone), db.ErrNotFound)
+	assert.ErrorIs(t, store.MoveTask(paint, db.DefaultList), db.ErrNotFound)
+	assert.ErrorIs(t, store.AddException(&db.Exception{TaskId: ids["Купить краску"], Date: "20240126"}), db.ErrNotFound)
+	assert.ErrorIs(t, store.DelException(ids["Купить краску"], "20240126"), db.ErrNotFound)
+	_, err = store.Exceptions(ids["Купить краску"])
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.ErrorIs(t, store.AdvanceTask(&db.Advance{TaskId: ids["Купить краску"], Archived: "2024-01-26T12:00:00Z"}), db.ErrNotFound)
+	assert.ErrorIs(t, store.AddDependency(ids["Черновик"], ids["Покрасить забор"]), db.ErrNotFound)
+
+	// задача в корзине никого не держит
+	task, err = store.GetTask(fence)
+	if assert.NoError(t, err) {
+		assert.False(t, task.Blocked)
+	}
+	assert.Equal(t, []string{"Покрасить забор"}, tagTitles(t, store, db.TaskQuery{Blocked: new(bool)}))
+	graph, err := store.TaskGraph(ids["Покрасить забор"])
+	if assert.NoError(t, err) {
+		assert.Empty(t, graph.Upstream)
+		assert.Empty(t, graph.Edges)
+	}
+
+	// корзина, сначала последние удаленные
+	tasks, err := store.Trash(10)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Черновик", "Купить краску"}, graphTitles(tasks))
+		assert.Equal(t, "2024-01-26T11:00:00Z", tasks[0].Deleted)
+	}
+
+	// возврат из корзины возвращает и метки, и зависимости, и историю
+	assert.NoError(t, store.RestoreTask(paint))
+	assert.ErrorIs(t, store.RestoreTask(paint), db.ErrNotFound)
+	assert.ErrorIs(t, store.RestoreTask(fence), db.ErrNotFound)
+	task, err = store.GetTask(paint)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"дом"}, task.Tags)
+		assert.Empty(t, task.Deleted)
+	}
+	task, err = store.GetTask(fence)
+	if assert.NoError(t, err) {
+		assert.True(t, task.Blocked)
+	}
+	completions, err = store.Completions("", "", 10)
+	if assert.NoError(t, err) {
+		assert.Len(t, completions, 1)
+	}
+
+	// окончательно удаляются только задачи, которые попали в корзину раньше срока
+	assert.NoError(t, store.TrashTask(paint, "2024-01-27T10:00:00Z"))
+	purged, err := store.PurgeTrash("2024-01-27T00:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	tasks, err = store.Trash(10)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Купить краску"}, graphTitles(tasks))
+	}
+	assert.ErrorIs(t, store.RestoreTask(draft), db.ErrNotFound)
+	purged, err = store.PurgeTrash("2024-01-28T00:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	tasks, err = store.Trash(10)
+	if assert.NoError(t, err) {
+		assert.Empty(t, tasks)
+	}
+	// вместе с задачей удаляются и ее зависимости
+	assert.ErrorIs(t, store.DelDependency(ids["Покрасить забор"], ids["Купить краску"]), db.ErrDependencyNotFound)
+}
+
+func TestTrashStore(t *testing.T) {
+	eachStore(t, checkTrash)
+}
+
+func TestTrashRetention(t *testing.T) {
+	for str, want := range map[string]time.Duration{
+		"":    config.DefaultTrashRetention,
+		"7d":  7 * 24 * time.Hour,
+		"90m": 90 * time.Minute,
+	} {
+		retention, err := config.ParseRetention(str)
+		assert.NoError(t, err)
+		assert.Equal(t, want, retention)
+	}
+	for _, str := range []string{"неделя", "d", "0d", "-1h"} {
+		_, err := config.ParseRetention(str)
+		assert.Error(t, err, str)
+	}
+}
+
+// функция возвращает заголовки задач из ответа со списком задач
+func trashTitles(ret map[string]any) []string {
+	titles := []string{}
+	tasks, _ := ret["tasks"].([]any)
+	for _, task := range tasks {
+		title, _ := task.(map[string]any)["title"].(string)
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+func TestTrashAPI(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	store := db.NewMemory()
+	h := handlers.New(store, config.Config{}, nextdate.FixedClock(now), nil)
+	srv := httptest.NewServer(server.NewServer(h, nil, "").Serv.Handler)
+	defer srv.Close()
+
+	_, ret := callJSON(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20240126", "title": "Отчет"})
+	id, _ := ret["id"].(string)
+
+	code, ret := callJSON(t, srv, http.MethodDelete, "/api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, ret)
+	code, _ = callJSON(t, srv, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/tasks", nil)
+	assert.Equal(t, float64(0), ret["total"])
+
+	code, ret = callJSON(t, srv, http.MethodGet, "/api/trash", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Отчет"}, trashTitles(ret))
+	if tasks, _ := ret["tasks"].([]any); assert.Len(t, tasks, 1) {
+		assert.Equal(t, "2024-01-26T12:00:00Z", tasks[0].(map[string]any)["deleted"])
+	}
+	code, ret = callJSON(t, srv, http.MethodGet, "/api/trash?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "limit must be from 1 to 1000", ret["error"])
+
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/trash/restore?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, ret)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, "Отчет", ret["title"])
+	assert.Nil(t, ret["deleted"])
+	code, ret = callJSON(t, srv, http.MethodPost, "/api/trash/restore?id="+id, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "task not found", ret["error"])
+
+	resp, _ := callV2(t, srv, http.MethodDelete, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = callV2(t, srv, http.MethodDelete, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/trash", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"Отчет"}, trashTitles(ret))
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/trash/"+id+"/restore", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Отчет", ret["title"])
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/trash/"+id+"/restore", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// очистка удаляет задачи, которые пролежали в корзине дольше срока хранения
+	code, _ = callJSON(t, srv, http.MethodDelete, "/api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	purged, err := h.PurgeTrash(time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+	later := handlers.New(store, config.Config{}, nextdate.FixedClock(now.Add(2*time.Hour)), nil)
+	purged, err = later.PurgeTrash(time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, ret = callJSON(t, srv, http.MethodGet, "/api/trash", nil)
+	assert.Empty(t, trashTitles(ret))
+}
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	ret, err := postJSON("api/task", map[string]any{"date": today, "title": "Корзина"}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.NotEmpty(t, task.Deleted)
+	ret, err = postJSON("api/trash", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, trashTitles(ret), "Корзина")
+
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Empty(t, task.Deleted)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
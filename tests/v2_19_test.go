@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// функция запроса к API v2 с произвольным телом; возвращает ответ и разобранный джисон
+func callV2(t *testing.T, srv *httptest.Server, method, path, body string) (*http.Response, map[string]any) {
+	req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader([]byte(body)))
+	assert.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var m map[string]any
+	if len(data) > 0 {
+		json.Unmarshal(data, &m)
+	}
+	return resp, m
+}
+
+func TestV2Tasks(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	resp, ret := callV2(t, srv, http.MethodPost, "/api/v2/tasks",
+		`{"date": "20240126", "title": "Полить цветы", "repeat": "d 3"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	id, _ := ret["id"].(string)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, "/api/v2/tasks/"+id, resp.Header.Get("Location"))
+
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Полить цветы", ret["title"])
+
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tasks", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, ret["tasks"], 1)
+
+	// поля, которых нет в запросе, кроме необязательных, становятся пустыми, как и в v1
+	resp, ret = callV2(t, srv, http.MethodPut, "/api/v2/tasks/"+id,
+		`{"date": "20240127", "title": "Полить кактус", "repeat": "d 3"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, id, ret["id"])
+	assert.Equal(t, "Полить кактус", ret["title"])
+
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240130", ret["date"])
+
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/skip", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240202", ret["date"])
+
+	resp, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/reschedule", `{"date": "20240203"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240203", ret["date"])
+
+	resp, _ = callV2(t, srv, http.MethodDelete, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, ret = callV2(t, srv, http.MethodGet, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "not_found", ret["code"])
+
+	// выполненная разовая задача удаляется
+	_, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks", `{"date": "20240126", "title": "Позвонить"}`)
+	id, _ = ret["id"].(string)
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = callV2(t, srv, http.MethodPost, "/api/v2/tasks/"+id+"/complete", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestV2Errors(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, config.Config{}, now)
+
+	_, ret := callV2(t, srv, http.MethodPost, "/api/v2/tasks", `{"date": "20240126", "title": "Позвонить"}`)
+	id, _ := ret["id"].(string)
+
+	for _, v := range []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{http.MethodPost, "/api/v2/tasks", `{"title": `, http.StatusBadRequest, "bad_request", ""},
+		{http.MethodPost, "/api/v2/tasks", `{"date": "20240126"}`, http.StatusUnprocessableEntity, "validation_failed", "title"},
+		{http.MethodPost, "/api/v2/tasks", `{"title": "Тест", "repeat": "d 500"}`, http.StatusUnprocessableEntity, "validation_failed", "repeat"},
+		{http.MethodPost, "/api/v2/tasks", `{"title": "Тест", "date": "26.01.2024"}`, http.StatusUnprocessableEntity, "validation_failed", ""},
+		{http.MethodGet, "/api/v2/tasks?limit=0", "", http.StatusUnprocessableEntity, "validation_failed", "limit"},
+		{http.MethodGet, "/api/v2/tasks/abc", "", http.StatusNotFound, "not_found", ""},
+		{http.MethodGet, "/api/v2/tasks/999999", "", http.StatusNotFound, "not_found", ""},
+		{http.MethodPut, "/api/v2/tasks/999999", `{"title": "Тест"}`, http.StatusNotFound, "not_found", ""},
+		{http.MethodDelete, "/api/v2/tasks/999999", "", http.StatusNotFound, "not_found", ""},
+		{http.MethodPut, "/api/v2/tasks/" + id, `{"id": "999999", "title": "Тест"}`, http.StatusConflict, "conflict", "id"},
+		{http.MethodPost, "/api/v2/tasks/" + id + "/skip", "", http.StatusConflict, "conflict", ""},
+		{http.MethodPost, "/api/v2/tasks/" + id + "/reschedule", `{"date": "завтра"}`, http.StatusUnprocessableEntity, "validation_failed", "date"},
+	} {
+		resp, ret := callV2(t, srv, v.method, v.path, v.body)
+		assert.Equal(t, v.status, resp.StatusCode, "%s %s %s", v.method, v.path, v.body)
+		assert.Equal(t, v.code, ret["code"], "%s %s %s", v.method, v.path, v.body)
+		assert.NotEmpty(t, ret["message"], "%s %s %s", v.method, v.path, v.body)
+		if v.field != "" {
+			details, _ := ret["details"].(map[string]any)
+			assert.Equal(t, v.field, details["field"], "%s %s %s", v.method, v.path, v.body)
+		}
+	}
+
+	// для ошибки в правиле повторения указано место ошибки
+	_, ret = callV2(t, srv, http.MethodPost, "/api/v2/tasks", `{"title": "Тест", "repeat": "d 500"}`)
+	details, _ := ret["details"].(map[string]any)
+	assert.Equal(t, "500", details["token"])
+	assert.Equal(t, float64(3), details["position"])
+
+	// неподдерживаемый метод
+	resp, _ := callV2(t, srv, http.MethodPatch, "/api/v2/tasks/"+id, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// без токена при заданном пароле
+	secured := newTestServer(t, config.Config{Password: "12345"}, now)
+	resp, ret = callV2(t, secured, http.MethodGet, "/api/v2/tasks", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "unauthorized", ret["code"])
+}
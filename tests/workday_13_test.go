@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrScorpio/finalTask/internal/nextdate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateWorkday(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "bd", ""},
+		{"20240101", "bd 0", ""},
+		{"20240101", "bd 5 /2", ""},
+		{"20240101", "mb 0", ""},
+		{"20240101", "mb 24", ""},
+		{"20240101", "mb 1 13", ""},
+		{"20240101", "m 3 shift=up", ""},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=3;X-SHIFT=UP", ""},
+		{"20240122", "bd 5", "20240129"},
+		{"20240126", "bd 1", "20240129"},
+		{"20240101", "mb 1", "20240201"},
+		{"20240101", "mb -1", "20240131"},
+		{"20240101", "mb 1 3,6", "20240301"},
+		{"20240101", "mb 2 /2", "20240304"},
+		{"20240101", "m 3 shift=next", "20240205"},
+		{"20240101", "m 3 shift=prev", "20240202"},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=3;X-SHIFT=NEXT", "20240205"},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+// функция возвращает дату в формате 20060102
+func testDate(t *testing.T, date string) time.Time {
+	tm, err := time.Parse("20060102", date)
+	assert.NoError(t, err)
+	return tm
+}
+
+func TestLoadCalendar(t *testing.T) {
+	cal, err := nextdate.LoadCalendar("testdata/holidays.ics", "testdata/holidays.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for date, want := range map[string]bool{
+		"20240101": false, // начало новогодних каникул
+		"20240108": false, // последний день каникул, DTEND не включается
+		"20240109": true,
+		"20250103": false, // каникулы повторяются каждый год
+		"20250109": true,
+		"20240307": true,
+		"20240308": false, // разовый праздник без DTEND
+		"20250308": false, // выпадает на субботу
+		"20240427": true,  // перенесенный рабочий день в субботу
+		"20240429": false,
+		"20240501": true,
+		"20240504": false,
+	} {
+		assert.Equal(t, want, cal.IsWorkday(testDate(t, date)), date)
+	}
+
+	// правило bd пропускает праздники из календаря
+	rule, err := nextdate.Parse("bd 1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rule.Calendar = cal
+	for after, want := range map[string]string{
+		"20231229": "20240109",
+		"20240307": "20240311",
+		"20240426": "20240427",
+		"20240427": "20240501",
+	} {
+		next, ok := rule.Next(testDate(t, after))
+		if assert.True(t, ok, after) {
+			assert.Equal(t, want, next.Format("20060102"), after)
+		}
+	}
+}
+
+func TestLoadCalendarError(t *testing.T) {
+	tbl := []struct {
+		file string
+		want string
+	}{
+		{"testdata/missing.json", "no such file"},
+		{"testdata/holidays.txt", "unknown calendar format"},
+		{"testdata/broken.json", "unexpected end of JSON input"},
+		{"testdata/baddate.json", `parsing time "2024-01-01"`},
+		{"testdata/nostart.ics", "event without DTSTART"},
+		{"testdata/badrule.ics", `"SOMETIMES" at position 6`},
+	}
+	for _, v := range tbl {
+		_, err := nextdate.LoadCalendar("testdata/holidays.ics", v.file)
+		if assert.Error(t, err, v.file) {
+			assert.Contains(t, err.Error(), "can't load holidays from "+v.file, v.file)
+			assert.Contains(t, err.Error(), v.want, v.file)
+		}
+	}
+}
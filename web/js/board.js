@@ -0,0 +1,104 @@
+// доска задач по статусам: колонки из /api/board, карточку можно перетащить в другую колонку
+(function () {
+    "use strict";
+
+    const columnNames = {
+        todo: "К выполнению",
+        in_progress: "В работе",
+        waiting: "Ожидание",
+        done: "Готово"
+    };
+    const priorityNames = { low: "низкий", normal: "обычный", high: "высокий", urgent: "срочный" };
+
+    // 20240126 -> 26.01.2024
+    function humanDate(d) {
+        return d.slice(6, 8) + "." + d.slice(4, 6) + "." + d.slice(0, 4);
+    }
+
+    // 90 -> 1 ч 30 мин
+    function humanEstimate(minutes) {
+        const h = Math.floor(minutes / 60);
+        const m = minutes % 60;
+        return (h ? h + " ч " : "") + (m ? m + " мин" : "");
+    }
+
+    function errorText(err) {
+        return err.response && err.response.data && err.response.data.error
+            ? err.response.data.error : String(err);
+    }
+
+    function card(task) {
+        const node = document.createElement("div");
+        const title = document.createElement("div");
+        const meta = document.createElement("div");
+        node.className = "card priority-" + task.priority;
+        node.draggable = true;
+        node.dataset.id = task.id;
+        title.textContent = task.title;
+        meta.className = "meta";
+        meta.textContent = [
+            humanDate(task.date) + (task.time ? " " + task.time : ""),
+            priorityNames[task.priority] || task.priority,
+            task.estimate ? humanEstimate(Number(task.estimate)) : ""
+        ].filter(Boolean).join(" · ");
+        node.appendChild(title);
+        node.appendChild(meta);
+        node.addEventListener("dragstart", function (e) {
+            e.dataTransfer.setData("text/plain", task.id);
+        });
+        return node;
+    }
+
+    function column(col, reload, status) {
+        const node = document.createElement("section");
+        const header = document.createElement("h2");
+        node.className = "column";
+        header.textContent = (columnNames[col.status] || col.status) + " (" + col.total + ")";
+        node.appendChild(header);
+        col.tasks.forEach(function (task) {
+            node.appendChild(card(task));
+        });
+        node.addEventListener("dragover", function (e) {
+            e.preventDefault();
+            node.classList.add("over");
+        });
+        node.addEventListener("dragleave", function () {
+            node.classList.remove("over");
+        });
+        node.addEventListener("drop", function (e) {
+            e.preventDefault();
+            node.classList.remove("over");
+            const params = new URLSearchParams({ id: e.dataTransfer.getData("text/plain"), status: col.status });
+            axios.post("api/task/status?" + params.toString()).then(reload).catch(function (err) {
+                status.textContent = errorText(err);
+            });
+        });
+        return node;
+    }
+
+    function init() {
+        const root = document.getElementById("board");
+        const status = document.getElementById("board-status");
+
+        function reload() {
+            axios.get("api/board").then(function (resp) {
+                root.innerHTML = "";
+                status.textContent = "";
+                resp.data.columns.forEach(function (col) {
+                    root.appendChild(column(col, reload, status));
+                });
+            }).catch(function (err) {
+                // без действующего токена отправляем на страницу входа
+                if (err.response && err.response.status === 401) {
+                    window.location.href = "/login.html";
+                    return;
+                }
+                status.textContent = errorText(err);
+            });
+        }
+
+        reload();
+    }
+
+    document.addEventListener("DOMContentLoaded", init);
+})();
@@ -0,0 +1,232 @@
+// страница документации API по описанию /api/openapi.json
+(function () {
+    "use strict";
+
+    const methods = ["get", "post", "put", "delete"];
+    let spec = null;
+
+    function el(tag, attrs, children) {
+        const node = document.createElement(tag);
+        Object.keys(attrs || {}).forEach(function (k) {
+            if (k === "text") {
+                node.textContent = attrs[k];
+            } else {
+                node.setAttribute(k, attrs[k]);
+            }
+        });
+        (children || []).forEach(function (c) {
+            node.appendChild(c);
+        });
+        return node;
+    }
+
+    // узел описания по ссылке вида #/components/schemas/Task
+    function resolve(node) {
+        while (node && node.$ref) {
+            node = node.$ref.replace(/^#\//, "").split("/").reduce(function (cur, part) {
+                return cur ? cur[part] : undefined;
+            }, spec);
+        }
+        return node || {};
+    }
+
+    // пример значения по схеме, чтобы показать структуру ответа
+    function example(schema, depth) {
+        schema = resolve(schema);
+        if (depth > 5) {
+            return null;
+        }
+        if (schema.example !== undefined) {
+            return schema.example;
+        }
+        if (schema.allOf) {
+            return schema.allOf.reduce(function (acc, s) {
+                return Object.assign(acc, example(s, depth + 1));
+            }, {});
+        }
+        if (schema.enum) {
+            return schema.enum[0];
+        }
+        switch (schema.type) {
+        case "object": {
+            const obj = {};
+            Object.keys(schema.properties || {}).forEach(function (k) {
+                obj[k] = example(schema.properties[k], depth + 1);
+            });
+            return obj;
+        }
+        case "array":
+            return [example(schema.items, depth + 1)];
+        case "integer":
+        case "number":
+            return schema.default !== undefined ? schema.default : 0;
+        case "boolean":
+            return false;
+        case "string":
+            return schema.format === "date-time" ? "2024-01-26T09:30:00+03:00" : "";
+        }
+        return null;
+    }
+
+    function contentExample(content) {
+        const type = Object.keys(content || {})[0];
+        if (!type) {
+            return null;
+        }
+        const value = example(content[type].schema, 0);
+        return type === "application/json" ? JSON.stringify(value, null, 2) : String(value);
+    }
+
+    function parametersTable(params, inputs) {
+        const rows = params.map(function (p) {
+            p = resolve(p);
+            const input = el("input", { name: p.name, placeholder: p.in });
+            inputs.push({ param: p, input: input });
+            return el("tr", {}, [
+                el("td", { text: p.name + (p.required ? " *" : "") }),
+                el("td", { text: p.in }),
+                el("td", { text: p.description || "" }),
+                el("td", {}, [input])
+            ]);
+        });
+        return el("table", {}, [
+            el("tr", {}, [el("th", { text: "параметр" }), el("th", { text: "где" }), el("th", { text: "описание" }), el("th", { text: "значение" })])
+        ].concat(rows));
+    }
+
+    // отправка запроса из формы операции
+    function tryIt(method, path, inputs, body, out) {
+        const query = new URLSearchParams();
+        inputs.forEach(function (i) {
+            const v = i.input.value;
+            if (i.param.in === "path") {
+                path = path.replace("{" + i.param.name + "}", encodeURIComponent(v));
+            } else if (v !== "") {
+                query.append(i.param.name, v);
+            }
+        });
+        const url = path + (query.toString() ? "?" + query.toString() : "");
+        const opts = { method: method.toUpperCase(), credentials: "same-origin" };
+        if (body) {
+            opts.body = body.value;
+            opts.headers = { "Content-Type": "application/json" };
+        }
+        out.textContent = "...";
+        fetch(url, opts).then(function (resp) {
+            return resp.text().then(function (text) {
+                try {
+                    text = JSON.stringify(JSON.parse(text), null, 2);
+                } catch (e) {
+                    // ответ не джисон, показываем как есть
+                }
+                out.textContent = resp.status + " " + resp.statusText + "\n" + text;
+            });
+        }).catch(function (err) {
+            out.textContent = String(err);
+        });
+    }
+
+    function operation(path, method, item, op) {
+        const params = (item.parameters || []).concat(op.parameters || []);
+        const inputs = [];
+        const body = el("div");
+        const children = [];
+        if (op.description) {
+            children.push(el("p", { text: op.description }));
+        }
+        if (op.security && op.security.length) {
+            children.push(el("p", { text: "Нужна кука token, если задан пароль." }));
+        }
+        if (params.length) {
+            children.push(el("h4", { text: "Параметры" }), parametersTable(params, inputs));
+        }
+        let bodyInput = null;
+        if (op.requestBody) {
+            const rb = resolve(op.requestBody);
+            bodyInput = el("textarea");
+            bodyInput.value = contentExample(rb.content) || "";
+            children.push(el("h4", { text: "Тело запроса" }), bodyInput);
+        }
+        children.push(el("h4", { text: "Ответы" }));
+        Object.keys(op.responses || {}).forEach(function (status) {
+            const resp = resolve(op.responses[status]);
+            children.push(el("p", {}, [
+                el("span", { class: "status", text: status + " " }),
+                el("span", { text: resp.description || "" })
+            ]));
+            const ex = contentExample(resp.content);
+            if (ex !== null) {
+                children.push(el("pre", { text: ex }));
+            }
+        });
+        const out = el("pre", { text: "" });
+        const button = el("button", { text: "Выполнить" });
+        button.addEventListener("click", function () {
+            tryIt(method, path, inputs, bodyInput, out);
+        });
+        children.push(button, out);
+        children.forEach(function (c) {
+            body.appendChild(c);
+        });
+        return el("details", { class: "op" }, [
+            el("summary", {}, [
+                el("span", { class: "method " + method, text: method.toUpperCase() }),
+                el("span", { class: "path", text: path + " " }),
+                el("span", { text: op.summary || "" })
+            ]),
+            body
+        ]);
+    }
+
+    function render() {
+        document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+        document.getElementById("description").textContent = spec.info.description || "";
+        const root = document.getElementById("docs");
+        root.innerHTML = "";
+        // операции группируются по первому тегу
+        const groups = {};
+        const order = (spec.tags || []).map(function (t) {
+            return t.name;
+        });
+        Object.keys(spec.paths).forEach(function (path) {
+            const item = spec.paths[path];
+            methods.forEach(function (method) {
+                const op = item[method];
+                if (!op) {
+                    return;
+                }
+                const tag = (op.tags || ["прочее"])[0];
+                if (order.indexOf(tag) < 0) {
+                    order.push(tag);
+                }
+                (groups[tag] = groups[tag] || []).push(operation(path, method, item, op));
+            });
+        });
+        order.forEach(function (tag) {
+            if (!groups[tag]) {
+                return;
+            }
+            const info = (spec.tags || []).find(function (t) {
+                return t.name === tag;
+            });
+            root.appendChild(el("h2", { text: tag }));
+            if (info && info.description) {
+                root.appendChild(el("p", { text: info.description }));
+            }
+            groups[tag].forEach(function (op) {
+                root.appendChild(op);
+            });
+        });
+    }
+
+    document.addEventListener("DOMContentLoaded", function () {
+        fetch("/api/openapi.json").then(function (resp) {
+            return resp.json();
+        }).then(function (data) {
+            spec = data;
+            render();
+        }).catch(function (err) {
+            document.getElementById("docs").textContent = "Не удалось загрузить описание: " + err;
+        });
+    });
+})();
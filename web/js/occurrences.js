@@ -0,0 +1,85 @@
+// предпросмотр следующих дат задачи по правилу повторения
+(function () {
+    "use strict";
+
+    function pad(n) {
+        return (n < 10 ? "0" : "") + n;
+    }
+
+    // 20240126 -> 26.01.2024
+    function humanDate(d) {
+        return d.slice(6, 8) + "." + d.slice(4, 6) + "." + d.slice(0, 4);
+    }
+
+    // 2024-01-26 -> 20240126
+    function apiDate(v) {
+        return v.replace(/-/g, "");
+    }
+
+    function today() {
+        const d = new Date();
+        return d.getFullYear() + "-" + pad(d.getMonth() + 1) + "-" + pad(d.getDate());
+    }
+
+    function init() {
+        const root = document.getElementById("occurrences");
+        if (!root) {
+            return;
+        }
+        const date = root.querySelector("[name=date]");
+        const repeat = root.querySelector("[name=repeat]");
+        const list = root.querySelector(".tags");
+        const status = root.querySelector(".occurrences-status");
+        date.value = today();
+
+        let timer = null;
+        let seq = 0;
+
+        function show(dates) {
+            list.innerHTML = "";
+            dates.forEach(function (d) {
+                const tag = document.createElement("div");
+                const text = document.createElement("span");
+                tag.className = "tag";
+                text.textContent = humanDate(d);
+                tag.appendChild(text);
+                list.appendChild(tag);
+            });
+            status.textContent = dates.length ? "" : "Дат не найдено";
+        }
+
+        function update() {
+            const rule = repeat.value.trim();
+            const cur = ++seq;
+            if (!rule) {
+                show([]);
+                status.textContent = "";
+                return;
+            }
+            const params = new URLSearchParams({ date: apiDate(date.value), repeat: rule, count: "10" });
+            axios.get("api/occurrences?" + params.toString()).then(function (resp) {
+                if (cur === seq) {
+                    show(resp.data);
+                }
+            }).catch(function (err) {
+                if (cur !== seq) {
+                    return;
+                }
+                list.innerHTML = "";
+                status.textContent = err.response && err.response.data && err.response.data.error
+                    ? err.response.data.error : String(err);
+            });
+        }
+
+        // пересчитываем с небольшой задержкой, пока пользователь печатает
+        function schedule() {
+            clearTimeout(timer);
+            timer = setTimeout(update, 300);
+        }
+
+        repeat.addEventListener("input", schedule);
+        date.addEventListener("input", schedule);
+    }
+
+    document.addEventListener("DOMContentLoaded", init);
+})();